@@ -36,97 +36,46 @@ var (
 	// ErrInvalidAcceptParam is the error returned when an invalid accept
 	// parameter is parsed
 	ErrInvalidAcceptParam = errors.New("Invalid Accept Parameter")
-)
-
-type (
-	// mediaRange is a string alias for an accept header's media range
-	mediaRange string
-
-	// mediaParams are a mapping of parameter to value argument strings. parsed
-	// from arguments of the form "foo=bar"
-	mediaParams map[string]string
 
-	// acceptExt is a type alias to mediaParams for parameters that follow the
-	// quality, "q", parameter in an accept header
-	acceptExt mediaParams
+	// ErrInvalidMediaParam is the error returned when an invalid media type
+	// parameter, such as a Content-Type parameter, is parsed
+	ErrInvalidMediaParam = errors.New("Invalid Media Type Parameter")
 )
 
-// Type returns the type of the media range instance. eg, a media range
-// "application/json" has a Type of "application"
-func (m mediaRange) Type() string {
-	var s = string(m)
-	var idx = strings.Index(s, "/")
-	if idx == -1 {
-		return ""
-	}
-	return s[:idx]
-}
-
-// SubType returns the sub-type of the media range instance. eg, a media range
-// "application/json" has a SubType of "json"
-func (m mediaRange) SubType() string {
-	var s = string(m)
-	var start = strings.Index(s, "/")
-	var end = strings.Index(s, ";")
-
-	if start == -1 {
-		return ""
-	}
-
-	if end < start {
-		return s[start+1:]
-	}
-	return s[start+1 : end]
-}
-
-// Return a structured media type suffix, as defined by RFC-6839, if one is
-// present. Eg, a media range of application/resource+json returns "json".
-// Because a these suffixes are optional, if no suffix is present an empty
-// string is returned
-func (m mediaRange) Suffix() string {
-	var s = string(m)
-	var end = strings.Index(s, ";")
-	var idx = strings.LastIndex(s, "+")
-	if idx == -1 {
-		return ""
-	}
-	return s[idx+1 : end]
-}
-
 // Accept is the struct representation of a single accept header value
 type Accept struct {
-	MediaRange   mediaRange
-	AcceptParams mediaParams
+	MediaRange   MediaType
+	AcceptParams MediaParams
 	Quality      float64
-	AcceptExt    acceptExt
+	AcceptExt    MediaParams
 }
 
 // NewAccept returns a zero-valued Accept instance
 func NewAccept() *Accept {
-	return &Accept{AcceptParams: make(mediaParams),
+	return &Accept{AcceptParams: make(MediaParams),
 		Quality:   -1.0,
-		AcceptExt: make(acceptExt)}
+		AcceptExt: make(MediaParams)}
 }
 
-// calculateQuality calculates the default quality of this accept value, if one
-// was not explicitly provided
-func (a *Accept) calculateQuality() {
-	if a.Quality != -1.0 {
+// calculateQuality calculates the default quality of this accept value
+// according to the provided policy, if one was not explicitly provided
+func (a *Accept) calculateQuality(policy NegotiationPolicy, explicit bool) {
+	if explicit {
 		return
-	} else if len(a.AcceptParams) > 0 {
-		a.Quality = AcceptParamsQuality
-	} else if a.MediaRange.SubType() != WildCard {
-		a.Quality = MediaRangeSubTypeQuality
-	} else if a.MediaRange.Type() != WildCard && a.MediaRange.SubType() == WildCard {
-		a.Quality = MediaRangeWildcardSubtypeQuality
-	} else if a.MediaRange.Type() == WildCard && a.MediaRange.SubType() == WildCard {
-		a.Quality = MediaRangeWildcardQuality
 	}
+	a.Quality = policy.defaultQuality(a)
 }
 
 // Parse parses the provided string argument into an Accept instance, returning
-// an error if the provided value is not properly formatted
+// an error if the provided value is not properly formatted. Default qualities
+// are assigned according to the LegacyPolicy
 func (a *Accept) Parse(accept string) error {
+	return a.parse(accept, LegacyPolicy)
+}
+
+// parse parses the provided string argument into an Accept instance, assigning
+// default qualities according to the provided policy
+func (a *Accept) parse(accept string, policy NegotiationPolicy) error {
 	var idx = strings.Index(accept, "/")
 	if idx == -1 {
 		return ErrInvalidMediaRange
@@ -137,12 +86,13 @@ func (a *Accept) Parse(accept string) error {
 
 	// if there was more than a simple media range provided, parse it and return
 	// any parsing errors that occur
+	var explicit bool
 	var err error
 	if idx != -1 {
-		err = a.parseAcceptParams(accept, idx)
+		explicit, err = a.parseAcceptParams(accept, idx)
 	}
 
-	a.calculateQuality()
+	a.calculateQuality(policy, explicit)
 	return err
 }
 
@@ -150,44 +100,135 @@ func (a *Accept) Parse(accept string) error {
 // optional ';' character
 func (a *Accept) parseMediaRange(accept string, semiIndex int) {
 	if semiIndex == -1 {
-		a.MediaRange = mediaRange(accept)
+		a.MediaRange = MediaType(accept)
 	} else {
-		a.MediaRange = mediaRange(accept[:semiIndex])
+		a.MediaRange = MediaType(accept[:semiIndex])
 	}
 }
 
-// parseMediaRange parses the optional accept parameters, up to an optional "q"
-// "quality" parameter, any the following accept extension parameters
-func (a *Accept) parseAcceptParams(accept string, semiIndex int) error {
-	var keyVal []string
+// parseAcceptParams parses the optional accept parameters, up to an optional "q"
+// "quality" parameter, any the following accept extension parameters. Accept
+// extensions may omit their value (e.g application/json;q=1;pretty). Whether a
+// quality was parsed is returned
+func (a *Accept) parseAcceptParams(accept string, semiIndex int) (bool, error) {
 	var qParsed bool
 	var err error
-	for _, param := range strings.Split(accept[semiIndex+1:], ";") {
-		keyVal = strings.Split(strings.TrimSpace(param), "=")
-		if len(keyVal) != 2 {
-			return ErrInvalidAcceptParam
+	for rest, more := accept[semiIndex+1:], true; more; {
+		var param string
+		param, rest, more = cutQuoted(rest, ';')
+
+		key, val, ok := parseParam(param)
+		if !ok && qParsed && isToken(strings.TrimSpace(param)) {
+			key, val, ok = strings.ToLower(strings.TrimSpace(param)), "", true
+		}
+		if !ok {
+			return qParsed, ErrInvalidAcceptParam
 		}
 
-		if keyVal[0] == "q" {
-			err = a.parseQuality(keyVal[1])
+		if key == "q" {
+			err = a.parseQuality(val)
 			if err != nil {
-				return err
+				return qParsed, err
 			}
 			qParsed = true
 		} else if qParsed {
-			a.AcceptExt[keyVal[0]] = keyVal[1]
+			if a.AcceptExt == nil {
+				a.AcceptExt = make(MediaParams)
+			}
+			a.AcceptExt[key] = val
 		} else {
-			a.AcceptParams[keyVal[0]] = keyVal[1]
+			if a.AcceptParams == nil {
+				a.AcceptParams = make(MediaParams)
+			}
+			a.AcceptParams[key] = val
 		}
 	}
-	return nil
+	return qParsed, nil
 }
 
-// parseQuality parses the value of a quality ("q") parameter value as a float
+// parseParam parses a single "key=value" parameter. Parameter names are case
+// insensitive and are returned in lower case, while quoted values are returned
+// with their surrounding quotes and escape characters removed
+func parseParam(param string) (string, string, bool) {
+	idx := strings.IndexByte(param, '=')
+	if idx == -1 {
+		return "", "", false
+	}
+
+	key := strings.ToLower(strings.TrimSpace(param[:idx]))
+	if len(key) == 0 {
+		return "", "", false
+	}
+	val := strings.TrimSpace(param[idx+1:])
+	if len(val) > 1 && val[0] == '"' && val[len(val)-1] == '"' {
+		val = unquote(val[1 : len(val)-1])
+	}
+	return key, val, true
+}
+
+// unquote removes the escape characters from the contents of a quoted string
+func unquote(s string) string {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitQuoted splits the provided string around each instance of sep which is
+// not contained within a quoted string
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	for more := true; more; {
+		var part string
+		part, s, more = cutQuoted(s, sep)
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// cutQuoted slices the provided string around the first instance of sep which
+// is not contained within a quoted string, returning the text before and after
+// it, and whether it was found. Unlike splitQuoted, it does not allocate
+func cutQuoted(s string, sep byte) (string, string, bool) {
+	var quoted bool
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// countQuoted returns the number of instances of sep in the provided string
+// which are not contained within a quoted string
+func countQuoted(s string, sep byte) int {
+	var n int
+	for more := true; more; n++ {
+		_, s, more = cutQuoted(s, sep)
+	}
+	return n - 1
+}
+
+// parseQuality parses the value of a quality ("q") parameter value as a float,
+// returning ErrInvalidAcceptParam if it is not a number between 0 and 1, as
+// defined by RFC-7231
 func (a *Accept) parseQuality(val string) error {
 	flt, err := strconv.ParseFloat(val, 64)
-	if err != nil {
-		return err
+	if err != nil || !(flt >= 0 && flt <= 1) {
+		return ErrInvalidAcceptParam
 	}
 
 	a.Quality = flt
@@ -195,12 +236,9 @@ func (a *Accept) parseQuality(val string) error {
 }
 
 // ParseAccept parses the provided accept header and returns a newly created
-// Accept struct, and a conditional error
+// Accept struct, and a conditional error. Default qualities are assigned
+// according to the LegacyPolicy. See NegotiationPolicy.ParseAccept for its
+// allocations
 func ParseAccept(header string) (*Accept, error) {
-	acpt := NewAccept()
-	err := acpt.Parse(header)
-	if err != nil {
-		return nil, err
-	}
-	return acpt, nil
+	return LegacyPolicy.ParseAccept(header)
 }
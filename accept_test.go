@@ -8,17 +8,17 @@ import (
 
 func TestMediaRange(t *testing.T) {
 	testio := []struct {
-		inp    mediaRange
+		inp    MediaType
 		typ    string
 		subtyp string
 		suffix string
 	}{
-		{mediaRange("application/json"), "application", "json", ""},
-		{mediaRange("application/*"), "application", "*", ""},
-		{mediaRange("*/*"), "*", "*", ""},
-		{mediaRange("application/json;indent=4"), "application", "json", ""},
-		{mediaRange("application/resource+json;indent=4"), "application", "resource+json", "json"},
-		{mediaRange("application resource"), "", "", ""},
+		{MediaType("application/json"), "application", "json", ""},
+		{MediaType("application/*"), "application", "*", ""},
+		{MediaType("*/*"), "*", "*", ""},
+		{MediaType("application/json;indent=4"), "application", "json", ""},
+		{MediaType("application/resource+json;indent=4"), "application", "resource+json", "json"},
+		{MediaType("application resource"), "", "", ""},
 	}
 
 	for _, test := range testio {
@@ -62,6 +62,12 @@ func TestAcceptParams(t *testing.T) {
 			map[string]string{"indent": "4"}},
 		{"application/json;indent=4; charset=utf8",
 			map[string]string{"indent": "4", "charset": "utf8"}},
+		{`application/json;profile="urn:x:v2"`,
+			map[string]string{"profile": "urn:x:v2"}},
+		{"application/json;Charset=utf8",
+			map[string]string{"charset": "utf8"}},
+		{`application/json;profile="urn:x:v2;a=b"`,
+			map[string]string{"profile": "urn:x:v2;a=b"}},
 	}
 
 	for _, test := range testio {
@@ -89,6 +95,8 @@ func TestBadParams(t *testing.T) {
 		{"application/json;q=0.3", false},
 		{"application/json;foo=bar", false},
 		{"application/json;foobar", true},
+		{"application/json;q=1;foobar", false},
+		{"application/json;q=1;foo bar", true},
 	}
 
 	for _, test := range testio {
@@ -133,6 +141,11 @@ func TestBadQuality(t *testing.T) {
 		{"application/json;q=0.3", false},
 		{"application/json;q=1", false},
 		{"application/json;q=foobar", true},
+		{"application/json;q=-1", true},
+		{"application/json;q=NaN", true},
+		{"application/json;q=Inf", true},
+		{"application/json;q=1.5", true},
+		{"application/json;q=0", false},
 	}
 
 	for _, test := range testio {
@@ -142,8 +155,18 @@ func TestBadQuality(t *testing.T) {
 			failed := err == nil && test.fail == true
 			assert.False(t, failed,
 				"Expected header %s to contain a bad quality value", test.inp)
+			if test.fail {
+				assert.Equal(t, ErrInvalidAcceptParam, err)
+			}
 		})
 	}
+
+	_, err := StrictPolicy.ParseHeader("text/html;q=-1, text/plain;q=0.5")
+	assert.Equal(t, ErrInvalidAcceptParam, err)
+
+	acpt, err := StrictPolicy.ParseAccept("text/html;q=0")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, acpt.Quality)
 }
 
 func TestAcceptExtensions(t *testing.T) {
@@ -156,6 +179,8 @@ func TestAcceptExtensions(t *testing.T) {
 			map[string]string{"version": "1"}},
 		{"application/json;indent=4; q=1.0; version=2",
 			map[string]string{"version": "2"}},
+		{"application/json;q=1.0;pretty; version=3",
+			map[string]string{"pretty": "", "version": "3"}},
 	}
 
 	for _, test := range testio {
@@ -172,3 +197,23 @@ func TestAcceptExtensions(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptParamsAllocated(t *testing.T) {
+	acpt := NewAccept()
+	acpt.AcceptParams["indent"] = "2"
+	acpt.AcceptExt["pretty"] = ""
+
+	header, err := ParseHeader("application/json, text/html")
+	assert.Nil(t, err)
+	for _, acpt := range header {
+		assert.NotNil(t, acpt.AcceptParams)
+		assert.NotNil(t, acpt.AcceptExt)
+	}
+
+	reg := NewRegistry()
+	reg.Register("application/json", testGeneric{})
+	_, acpt, err = reg.Negotiate("application/json")
+	assert.Nil(t, err)
+	acpt.AcceptParams["indent"] = "2"
+	acpt.AcceptExt["pretty"] = ""
+}
@@ -0,0 +1,103 @@
+package negotiator
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// MultipartMixedMediaType is the media type of a batch of representations,
+// each with its own Content-Type
+const MultipartMixedMediaType = "multipart/mixed"
+
+// batchEnvelope is a media type the outer envelope of a batch response may be
+// rendered as
+type batchEnvelope MediaType
+
+// formatType returns the media type of the batch envelope
+func (e batchEnvelope) formatType() MediaType {
+	return MediaType(e)
+}
+
+// batchEnvelopes is the Registry used to negotiate the outer envelope of a
+// batch response
+var batchEnvelopes = newFormatRegistry(batchEnvelope(MultipartMixedMediaType))
+
+// MarshalBatch marshals each of the provided ContentNegotiators, based on an
+// Accept, as a part of a multipart/mixed body written to the provided
+// io.Writer. Each part's Content-Type is provided by its ContentNegotiator's
+// ContentType method. The Content-Type of the multipart/mixed body, including
+// its boundary, is returned
+func MarshalBatch(w io.Writer, acpt *Accept, items ...ContentNegotiator) (string, error) {
+	mw := multipart.NewWriter(w)
+	for _, cn := range items {
+		contentType, err := cn.ContentType(acpt)
+		if err != nil {
+			return "", err
+		}
+		data, err := cn.MarshalMedia(acpt)
+		if err != nil {
+			return "", err
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set(ContentTypeHeader, contentType)
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write(data); err != nil {
+			return "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	return MultipartMixedMediaType + "; boundary=" + mw.Boundary(), nil
+}
+
+// batchAccept returns the Accept used to marshal each part of a batch
+// response, which is the most preferred acceptable media range of the header
+// that is not itself a multipart media range, or */* if there is none
+func batchAccept(header AcceptHeader) *Accept {
+	for _, acpt := range header {
+		if acpt.MediaRange.Type() != "multipart" && acpt.Quality > 0 {
+			return acpt
+		}
+	}
+
+	acpt, _ := StrictPolicy.ParseAccept(WildCard + "/" + WildCard)
+	return acpt
+}
+
+// WriteBatch writes the provided ContentNegotiators to w as a multipart/mixed
+// batch response. The request's Accept header must accept multipart/mixed,
+// otherwise ErrNoContentType is returned and nothing is written. Each part is
+// marshalled using the most preferred media range of the Accept header which
+// is not a multipart media range, allowing a client to request, for example,
+// "multipart/mixed, application/json"
+func WriteBatch(w http.ResponseWriter, req *http.Request, items ...ContentNegotiator) error {
+	if _, err := negotiateFormat(batchEnvelopes, req); err != nil {
+		return err
+	}
+
+	acceptHeader, err := StrictPolicy.ParseHeader(acceptOrAny(req.Header.Get(AcceptHeaderKey)))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	contentType, err := MarshalBatch(&buf, batchAccept(acceptHeader), items...)
+	if err != nil {
+		return err
+	}
+
+	AddVary(w.Header(), AcceptHeaderKey)
+	w.Header().Set(ContentTypeHeader, contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = buf.WriteTo(w)
+	return err
+}
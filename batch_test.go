@@ -0,0 +1,106 @@
+package negotiator
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBatchCN implements the ContentNegotiator interface, rendering itself in
+// whichever media type it is asked for
+type testBatchCN struct {
+	body string
+	err  error
+}
+
+func (b *testBatchCN) ContentType(a *Accept) (string, error) {
+	if a.MediaRange.IsWildcard() {
+		return "text/plain", nil
+	}
+	return string(a.MediaRange), nil
+}
+
+func (b *testBatchCN) MarshalMedia(a *Accept) ([]byte, error) {
+	return []byte(b.body), b.err
+}
+
+func (b *testBatchCN) UnmarshalMedia(string, ContentTypeParams, []byte) error {
+	return nil
+}
+
+func readBatch(t *testing.T, contentType string, body []byte) ([]string, []string) {
+	ct, err := ParseContentType(contentType)
+	assert.Nil(t, err)
+	assert.Equal(t, MediaType(MultipartMixedMediaType), ct.MediaType)
+
+	var types, bodies []string
+	reader := multipart.NewReader(bytes.NewReader(body), ct.Boundary())
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(part)
+		types = append(types, part.Header.Get(ContentTypeHeader))
+		bodies = append(bodies, string(data))
+	}
+	return types, bodies
+}
+
+func TestMarshalBatch(t *testing.T) {
+	var buf bytes.Buffer
+	acpt, _ := ParseAccept("application/json")
+	contentType, err := MarshalBatch(&buf, acpt,
+		&testBatchCN{body: `{"a":1}`}, &testBatchCN{body: `{"b":2}`})
+	assert.Nil(t, err)
+
+	types, bodies := readBatch(t, contentType, buf.Bytes())
+	assert.Equal(t, []string{"application/json", "application/json"}, types)
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, bodies)
+
+	errMarshal := errors.New("marshal failed")
+	_, err = MarshalBatch(&buf, acpt, &testBatchCN{err: errMarshal})
+	assert.Equal(t, errMarshal, err)
+}
+
+func TestWriteBatch(t *testing.T) {
+	testio := []struct {
+		accept string
+		types  []string
+		err    error
+	}{
+		{"", []string{"text/plain", "text/plain"}, nil},
+		{"multipart/mixed", []string{"text/plain", "text/plain"}, nil},
+		{"multipart/*, application/json", []string{"application/json", "application/json"}, nil},
+		{"application/xml;q=0.5, multipart/mixed, application/json",
+			[]string{"application/json", "application/json"}, nil},
+		{"application/json", nil, ErrNoContentType},
+		{"multipart/mixed;q=0, */*", nil, ErrNoContentType},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/batch", nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			w := httptest.NewRecorder()
+
+			err := WriteBatch(w, req, &testBatchCN{body: "one"}, &testBatchCN{body: "two"})
+			assert.Equal(t, test.err, err)
+			if err != nil {
+				assert.Equal(t, 0, w.Body.Len())
+				return
+			}
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			types, bodies := readBatch(t, w.Header().Get(ContentTypeHeader), w.Body.Bytes())
+			assert.Equal(t, test.types, types)
+			assert.Equal(t, []string{"one", "two"}, bodies)
+		})
+	}
+}
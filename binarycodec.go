@@ -0,0 +1,174 @@
+package negotiator
+
+import "reflect"
+
+const (
+	// ProtobufMediaType is the media type of a Protocol Buffers message
+	ProtobufMediaType = "application/protobuf"
+
+	// XProtobufMediaType is the legacy, unregistered media type of a Protocol
+	// Buffers message
+	XProtobufMediaType = "application/x-protobuf"
+
+	// MsgpackMediaType is the media type of a MessagePack document
+	MsgpackMediaType = "application/msgpack"
+)
+
+// A SelectiveCodec is a Codec which can only represent some values. When a
+// SelectiveCodec does not support a value, a Media represents it using the
+// Codecs' Fallback media type instead
+type SelectiveCodec interface {
+	Codec
+
+	// Supports returns true if the provided value can be marshalled
+	Supports(v interface{}) bool
+}
+
+// pointerTo returns the provided value if it is a pointer, or a pointer to a
+// copy of it otherwise, so that methods with pointer receivers, as generated
+// for Protocol Buffers and MessagePack types, may be called on values returned
+// by a Registry
+func pointerTo(v interface{}) interface{} {
+	if v == nil || reflect.TypeOf(v).Kind() == reflect.Ptr {
+		return v
+	}
+	ptr := reflect.New(reflect.TypeOf(v))
+	ptr.Elem().Set(reflect.ValueOf(v))
+	return ptr.Interface()
+}
+
+// ProtoMessage is implemented by Protocol Buffers messages which marshal and
+// unmarshal themselves, such as those generated by gogo/protobuf. Messages
+// generated by protoc-gen-go do not implement it, and must be marshalled by a
+// ProtobufCodec's MarshalFunc and UnmarshalFunc instead
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+// ProtobufCodec is a SelectiveCodec which marshals and unmarshals Protocol
+// Buffers messages. By default only values implementing ProtoMessage are
+// supported. Other messages, such as those generated by protoc-gen-go, are
+// supported by providing all of IsMessageFunc, MarshalFunc and UnmarshalFunc,
+// which are given a pointer to the value:
+//
+//	codec := ProtobufCodec{
+//		IsMessageFunc: func(v interface{}) bool {
+//			_, ok := v.(proto.Message)
+//			return ok
+//		},
+//		MarshalFunc: func(v interface{}) ([]byte, error) {
+//			return proto.Marshal(v.(proto.Message))
+//		},
+//		UnmarshalFunc: func(data []byte, v interface{}) error {
+//			return proto.Unmarshal(data, v.(proto.Message))
+//		},
+//	}
+type ProtobufCodec struct {
+	// IsMessageFunc returns true if the provided value can be marshalled by
+	// MarshalFunc and unmarshalled by UnmarshalFunc
+	IsMessageFunc func(v interface{}) bool
+
+	// MarshalFunc renders the provided message in the Protocol Buffers wire
+	// format
+	MarshalFunc func(v interface{}) ([]byte, error)
+
+	// UnmarshalFunc parses the Protocol Buffers wire format into the provided
+	// message
+	UnmarshalFunc func(data []byte, v interface{}) error
+}
+
+// pluggable returns true if the provided value is a message supported by the
+// ProtobufCodec's IsMessageFunc, MarshalFunc and UnmarshalFunc
+func (c ProtobufCodec) pluggable(v interface{}) bool {
+	return c.IsMessageFunc != nil && c.MarshalFunc != nil &&
+		c.UnmarshalFunc != nil && c.IsMessageFunc(v)
+}
+
+// Supports returns true if the provided value, or a pointer to it, implements
+// ProtoMessage or is supported by the ProtobufCodec's IsMessageFunc
+func (c ProtobufCodec) Supports(v interface{}) bool {
+	v = pointerTo(v)
+	_, ok := v.(ProtoMessage)
+	return ok || c.pluggable(v)
+}
+
+// Marshal renders the provided message in the Protocol Buffers wire format,
+// or returns ErrUnsupportedType for any other value
+func (c ProtobufCodec) Marshal(v interface{}, _ *Accept) ([]byte, error) {
+	v = pointerTo(v)
+	if c.pluggable(v) {
+		return c.MarshalFunc(v)
+	}
+	msg, ok := v.(ProtoMessage)
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	return msg.Marshal()
+}
+
+// Unmarshal parses the Protocol Buffers wire format into the provided
+// message, or returns ErrUnsupportedType for any other value
+func (c ProtobufCodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	if c.pluggable(v) {
+		return c.UnmarshalFunc(data, v)
+	}
+	msg, ok := v.(ProtoMessage)
+	if !ok {
+		return ErrUnsupportedType
+	}
+	return msg.Unmarshal(data)
+}
+
+// MsgpackMessage is implemented by values which marshal and unmarshal
+// themselves as MessagePack, such as those generated by tinylib/msgp
+type MsgpackMessage interface {
+	MarshalMsg([]byte) ([]byte, error)
+	UnmarshalMsg([]byte) ([]byte, error)
+}
+
+// MsgpackCodec is a SelectiveCodec which marshals and unmarshals values
+// implementing MsgpackMessage
+type MsgpackCodec struct{}
+
+// Supports returns true if the provided value, or a pointer to it, implements
+// MsgpackMessage
+func (MsgpackCodec) Supports(v interface{}) bool {
+	_, ok := pointerTo(v).(MsgpackMessage)
+	return ok
+}
+
+// Marshal renders the provided MsgpackMessage as MessagePack, or returns
+// ErrUnsupportedType for any other value
+func (MsgpackCodec) Marshal(v interface{}, _ *Accept) ([]byte, error) {
+	msg, ok := pointerTo(v).(MsgpackMessage)
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	return msg.MarshalMsg(nil)
+}
+
+// Unmarshal parses MessagePack into the provided MsgpackMessage, or returns
+// ErrUnsupportedType for any other value
+func (MsgpackCodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	msg, ok := v.(MsgpackMessage)
+	if !ok {
+		return ErrUnsupportedType
+	}
+	_, err := msg.UnmarshalMsg(data)
+	return err
+}
+
+// NewBinaryCodecs returns Codecs which represent values as JSON, Protocol
+// Buffers or MessagePack. JSON is registered first, so that clients accepting
+// any media type receive JSON, and is the Fallback for values which can not
+// be represented in a binary format
+func NewBinaryCodecs() *Codecs {
+	codecs := NewCodecs()
+	codecs.Register(JSONMediaType, JSONCodec{})
+	codecs.Register(ProtobufMediaType, ProtobufCodec{})
+	codecs.Register(XProtobufMediaType, ProtobufCodec{})
+	codecs.Register(MsgpackMediaType, MsgpackCodec{})
+	codecs.Fallback = JSONMediaType
+	return codecs
+}
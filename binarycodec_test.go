@@ -0,0 +1,165 @@
+package negotiator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBinaryMessage implements both ProtoMessage and MsgpackMessage, encoding
+// itself as its raw payload prefixed by the name of the format
+type testBinaryMessage struct {
+	Payload string `json:"payload"`
+}
+
+func (m *testBinaryMessage) Marshal() ([]byte, error) {
+	return []byte("proto:" + m.Payload), nil
+}
+
+func (m *testBinaryMessage) Unmarshal(data []byte) error {
+	m.Payload = string(data)
+	return nil
+}
+
+func (m *testBinaryMessage) MarshalMsg(b []byte) ([]byte, error) {
+	return append(b, "msgpack:"+m.Payload...), nil
+}
+
+func (m *testBinaryMessage) UnmarshalMsg(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("short msgpack")
+	}
+	m.Payload = string(data)
+	return nil, nil
+}
+
+func TestBinaryCodecsMarshal(t *testing.T) {
+	testio := []struct {
+		accept   string
+		value    interface{}
+		cType    string
+		expected string
+	}{
+		{"*/*", &testBinaryMessage{"a"}, JSONMediaType, `{"payload":"a"}`},
+		{"application/json", &testBinaryMessage{"a"}, JSONMediaType, `{"payload":"a"}`},
+		{"application/x-protobuf", &testBinaryMessage{"a"}, XProtobufMediaType, "proto:a"},
+		{"application/protobuf", &testBinaryMessage{"a"}, ProtobufMediaType, "proto:a"},
+		{"application/msgpack", &testBinaryMessage{"a"}, MsgpackMediaType, "msgpack:a"},
+		{"application/protobuf, application/json;q=0.5", &testCodecModel{"b", 1},
+			JSONMediaType, `{"name":"b","count":1}`},
+		{"application/msgpack, */*;q=0.1", &testCodecModel{"b", 1},
+			JSONMediaType, `{"name":"b","count":1}`},
+	}
+
+	for _, test := range testio {
+		registry := NewRegistry()
+		registry.RegisterCodecs(test.value, NewBinaryCodecs())
+		val, acpt, err := registry.Negotiate(test.accept)
+		assert.Nil(t, err)
+
+		media := NewMedia(val, NewBinaryCodecs())
+		cType, err := media.ContentType(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.cType, cType)
+
+		data, err := media.MarshalMedia(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+}
+
+func TestBinaryCodecsUnacceptableFallback(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterCodecs(&testCodecModel{}, NewBinaryCodecs())
+	assert.Equal(t, []MediaType{JSONMediaType}, registry.MediaTypes())
+	_, _, err := registry.Negotiate(XProtobufMediaType)
+	assert.Equal(t, ErrNoContentType, err)
+
+	media := NewMedia(&testCodecModel{}, NewBinaryCodecs())
+	acpt, _ := ParseAccept(XProtobufMediaType)
+	_, err = media.ContentType(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+	_, err = media.MarshalMedia(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+
+	codecs := NewCodecs()
+	codecs.Register(ProtobufMediaType, ProtobufCodec{})
+	codecs.Register(JSONMediaType, JSONCodec{})
+	codecs.Fallback = JSONMediaType
+	media = NewMedia(&testCodecModel{}, codecs)
+	acpt, _ = ParseAccept("application/*")
+	cType, err := media.ContentType(acpt)
+	assert.Nil(t, err)
+	assert.Equal(t, JSONMediaType, cType)
+}
+
+func TestBinaryCodecsNoFallback(t *testing.T) {
+	codecs := NewBinaryCodecs()
+	codecs.Fallback = ""
+	media := NewMedia(&testCodecModel{}, codecs)
+
+	acpt, _ := ParseAccept(ProtobufMediaType)
+	_, err := media.ContentType(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+	_, err = media.MarshalMedia(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+}
+
+func TestBinaryCodecsUnmarshal(t *testing.T) {
+	msg := &testBinaryMessage{}
+	media := NewMedia(msg, NewBinaryCodecs())
+
+	assert.Nil(t, media.UnmarshalMedia(XProtobufMediaType, nil, []byte("p")))
+	assert.Equal(t, "p", msg.Payload)
+	assert.Nil(t, media.UnmarshalMedia(MsgpackMediaType, nil, []byte("m")))
+	assert.Equal(t, "m", msg.Payload)
+	assert.NotNil(t, media.UnmarshalMedia(MsgpackMediaType, nil, nil))
+	assert.Nil(t, media.UnmarshalMedia(JSONMediaType, nil, []byte(`{"payload":"j"}`)))
+	assert.Equal(t, "j", msg.Payload)
+
+	media = NewMedia(&testCodecModel{}, NewBinaryCodecs())
+	assert.Equal(t, ErrUnsupportedType, media.UnmarshalMedia(ProtobufMediaType, nil, nil))
+	assert.Equal(t, ErrUnsupportedType, media.UnmarshalMedia(MsgpackMediaType, nil, nil))
+}
+
+// testPluggableMessage does not implement ProtoMessage, and is marshalled by
+// the functions of a ProtobufCodec
+type testPluggableMessage struct {
+	Payload string
+}
+
+func TestProtobufCodecFuncs(t *testing.T) {
+	codec := ProtobufCodec{
+		IsMessageFunc: func(v interface{}) bool {
+			_, ok := v.(*testPluggableMessage)
+			return ok
+		},
+		MarshalFunc: func(v interface{}) ([]byte, error) {
+			return []byte("pluggable:" + v.(*testPluggableMessage).Payload), nil
+		},
+		UnmarshalFunc: func(data []byte, v interface{}) error {
+			v.(*testPluggableMessage).Payload = string(data)
+			return nil
+		},
+	}
+
+	assert.True(t, codec.Supports(testPluggableMessage{"a"}))
+	assert.True(t, codec.Supports(&testBinaryMessage{}))
+	assert.False(t, codec.Supports(testCodecModel{}))
+	assert.False(t, ProtobufCodec{}.Supports(testPluggableMessage{}))
+
+	data, err := codec.Marshal(testPluggableMessage{"a"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "pluggable:a", string(data))
+	data, err = codec.Marshal(&testBinaryMessage{"b"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "proto:b", string(data))
+	_, err = codec.Marshal(testCodecModel{}, nil)
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	msg := &testPluggableMessage{}
+	assert.Nil(t, codec.Unmarshal([]byte("c"), ProtobufMediaType, nil, msg))
+	assert.Equal(t, "c", msg.Payload)
+	assert.Equal(t, ErrUnsupportedType, codec.Unmarshal(nil, ProtobufMediaType, nil, &testCodecModel{}))
+}
@@ -0,0 +1,177 @@
+package negotiator
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats reports the effectiveness of a Registry's negotiation cache
+type CacheStats struct {
+	// Hits is the number of negotiations answered by the cache
+	Hits uint64
+
+	// Misses is the number of negotiations which were not cached
+	Misses uint64
+
+	// Entries is the number of accept headers currently cached
+	Entries int
+
+	// Size is the maximum number of accept headers the cache holds
+	Size int
+}
+
+// cacheEntry is the memoized result of negotiating a single accept header
+type cacheEntry struct {
+	header  string
+	choices []*candidate
+	err     error
+}
+
+// negotiationCache is a bounded, least recently used cache of negotiation
+// results, keyed by the raw accept header
+type negotiationCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+// newNegotiationCache returns an empty negotiationCache holding up to size
+// accept headers
+func newNegotiationCache(size int) *negotiationCache {
+	return &negotiationCache{size: size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New()}
+}
+
+// get returns the cached result of negotiating the provided accept header,
+// and false if it is not cached
+func (c *negotiationCache) get(header string) ([]*candidate, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[header]
+	if !ok {
+		c.misses++
+		return nil, nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	return entry.choices, entry.err, true
+}
+
+// put caches the result of negotiating the provided accept header, evicting
+// the least recently used header if the cache is full
+func (c *negotiationCache) put(header string, choices []*candidate, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[header]; ok {
+		elem.Value = &cacheEntry{header, choices, err}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[header] = c.order.PushFront(&cacheEntry{header, choices, err})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).header)
+	}
+}
+
+// reset removes every cached result, keeping the hit and miss counters
+func (c *negotiationCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element, c.size)
+	c.order.Init()
+}
+
+// stats returns the cache's CacheStats
+func (c *negotiationCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits,
+		Misses:  c.misses,
+		Entries: c.order.Len(),
+		Size:    c.size}
+}
+
+// clone returns a deep copy of the Accept, so that a cached or pooled Accept is
+// never modified by the caller it is returned to. Like NewAccept, the copy's
+// AcceptParams and AcceptExt are never nil
+func (a *Accept) clone() *Accept {
+	c := *a
+	c.AcceptParams = a.AcceptParams.clone()
+	c.AcceptExt = a.AcceptExt.clone()
+	return &c
+}
+
+// clone returns a copy of the MediaParams
+func (m MediaParams) clone() MediaParams {
+	c := make(MediaParams, len(m))
+	for key, val := range m {
+		c[key] = val
+	}
+	return c
+}
+
+// cloneAccepts replaces the Accept of each of the provided candidates with a
+// clone of it. Consecutive candidates matching the same Accept, as is common
+// when a wildcard media range matches many registrations, share a clone
+func cloneAccepts(choices []*candidate) {
+	var last, clone *Accept
+	for _, c := range choices {
+		if c.accept != last {
+			last, clone = c.accept, c.accept.clone()
+		}
+		c.accept = clone
+	}
+}
+
+// cloneCandidates returns copies of the provided candidates, with cloned
+// Accepts
+func cloneCandidates(choices []*candidate) []*candidate {
+	clones := make([]*candidate, len(choices))
+	for i, c := range choices {
+		clone := *c
+		clones[i] = &clone
+	}
+	cloneAccepts(clones)
+	return clones
+}
+
+// SetCacheSize enables a least recently used cache of up to size negotiation
+// results, keyed by the raw accept header, which avoids parsing and matching
+// repeated accept headers. Any previously cached results are discarded, and a
+// size of 0 or less disables the cache. The cache is invalidated whenever the
+// Registry's registrations change. Values returned by an ExtensionNegotiator
+// are reused when a cached accept header is negotiated again
+func (r *Registry) SetCacheSize(size int) {
+	if size <= 0 {
+		r.cache = nil
+		return
+	}
+	r.cache = newNegotiationCache(size)
+}
+
+// CacheStats returns the hit and miss counters and occupancy of the Registry's
+// negotiation cache. The zero CacheStats is returned if caching is disabled
+func (r *Registry) CacheStats() CacheStats {
+	if r.cache == nil {
+		return CacheStats{}
+	}
+	return r.cache.stats()
+}
+
+// invalidate discards any cached negotiation results
+func (r *Registry) invalidate() {
+	if r.cache != nil {
+		r.cache.reset()
+	}
+}
@@ -0,0 +1,115 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryCache(t *testing.T) {
+	reg := NewRegistry()
+	assert.Equal(t, CacheStats{}, reg.CacheStats())
+
+	reg.SetCacheSize(2)
+	reg.Register("application/json", testGeneric{})
+
+	for i := 0; i < 3; i++ {
+		val, acpt, err := reg.Negotiate("application/json;indent=2")
+		assert.Nil(t, err)
+		assert.Equal(t, testGeneric{}, val)
+		assert.Equal(t, MediaParams{"indent": "2"}, acpt.AcceptParams)
+		acpt.AcceptParams["indent"] = "4"
+	}
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1, Size: 2}, reg.CacheStats())
+
+	for i := 0; i < 2; i++ {
+		_, _, err := reg.Negotiate("application/xml")
+		assert.Equal(t, ErrNoContentType, err)
+	}
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 2, Entries: 2, Size: 2}, reg.CacheStats())
+
+	_, _, err := reg.Negotiate("application/json;q=0.5")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 3, Entries: 2, Size: 2}, reg.CacheStats())
+
+	// the least recently used header was evicted
+	_, _, err = reg.Negotiate("application/json;indent=2")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 4, Entries: 2, Size: 2}, reg.CacheStats())
+
+	// registering a type invalidates cached results
+	reg.Register("application/xml", testSpecific{})
+	assert.Equal(t, 0, reg.CacheStats().Entries)
+	val, _, err := reg.Negotiate("application/xml")
+	assert.Nil(t, err)
+	assert.Equal(t, testSpecific{}, val)
+
+	reg.DeclareAcceptParams("application/xml")
+	assert.Equal(t, 0, reg.CacheStats().Entries)
+	reg.Negotiate("application/xml")
+	reg.DeclareExtensions("application/xml")
+	assert.Equal(t, 0, reg.CacheStats().Entries)
+
+	selected := reg.Select(func(MediaType) bool { return true })
+	assert.Equal(t, CacheStats{Size: 2}, selected.CacheStats())
+
+	reg.SetCacheSize(0)
+	assert.Equal(t, CacheStats{}, reg.CacheStats())
+}
+
+func TestRegistryCacheChoices(t *testing.T) {
+	reg := NewRegistryWithPolicy(StrictPolicy)
+	reg.SetCacheSize(8)
+	reg.Register("application/json", testGeneric{})
+	reg.Register("application/xml", testSpecific{})
+
+	for i := 0; i < 2; i++ {
+		choices, err := reg.Choices("*/*")
+		assert.Nil(t, err)
+		assert.Equal(t, []MediaType{"application/json", "application/xml"}, choices)
+
+		_, err = reg.Choices("application/json;foo")
+		assert.Equal(t, ErrInvalidAcceptParam, err)
+	}
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Entries: 2, Size: 8}, reg.CacheStats())
+}
+
+func TestCloneAccepts(t *testing.T) {
+	for _, size := range []int{0, 8} {
+		reg := NewRegistryWithPolicy(StrictPolicy)
+		reg.SetCacheSize(size)
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xml", testSpecific{})
+		reg.Register("text/csv", testSpecific{})
+
+		for i := 0; i < 2; i++ {
+			choices, err := reg.negotiate("application/*, text/*")
+			assert.Nil(t, err)
+			assert.Equal(t, 3, len(choices))
+			assert.True(t, choices[0].accept == choices[1].accept)
+			assert.False(t, choices[1].accept == choices[2].accept)
+			assert.NotNil(t, choices[2].accept.AcceptParams)
+		}
+	}
+}
+
+func BenchmarkRegistryNegotiate(b *testing.B) {
+	header := "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
+	for _, size := range []int{0, 64} {
+		reg := NewRegistry()
+		reg.SetCacheSize(size)
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xhtml+xml", testSpecific{})
+
+		name := "uncached"
+		if size > 0 {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				reg.Negotiate(header)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// maxJSONIndent is the largest indent a JSONCodec honors
+const maxJSONIndent = 16
+
+// A Codec marshals and unmarshals values of any type to and from a family of
+// media types, allowing a resource to be negotiated without implementing a
+// ContentNegotiator for each of its representations
+type Codec interface {
+	// Marshal renders the provided value as the media range of the Accept
+	Marshal(v interface{}, acpt *Accept) ([]byte, error)
+
+	// Unmarshal parses data of the provided content type and parameters into
+	// the provided value, which must be a pointer
+	Unmarshal(data []byte, cType string, params ContentTypeParams, v interface{}) error
+}
+
+// A ParamCodec is a Codec which describes the media type parameters of the
+// representations it produces, such as the header parameter of text/csv
+type ParamCodec interface {
+	Codec
+
+	// Params returns the parameters of the representation rendered for the
+	// provided Accept
+	Params(acpt *Accept) MediaParams
+}
+
+// An AcceptParamCodec is a Codec which declares the accept-params it
+// understands. A Registry whose NegotiationPolicy rejects unknown params will
+// not negotiate its media types for media ranges with any other accept-params
+type AcceptParamCodec interface {
+	Codec
+
+	// AcceptParams returns the names of the accept-params the Codec
+	// understands
+	AcceptParams() []string
+}
+
+// JSONCodec is a Codec which marshals and unmarshals values using the
+// encoding/json package. JSON is indented by the number of spaces given by an
+// indent accept-param (e.g application/json;indent=4), or by two spaces if a
+// pretty accept-ext or accept-param is present (e.g application/json;q=1;pretty)
+type JSONCodec struct{}
+
+// AcceptParams returns the indent and pretty accept-params
+func (JSONCodec) AcceptParams() []string {
+	return []string{"indent", "pretty"}
+}
+
+// Marshal renders the provided value as JSON, indented as requested by the
+// provided Accept. ErrInvalidAcceptParam is returned if its indent is not a
+// number between 0 and 16
+func (JSONCodec) Marshal(v interface{}, acpt *Accept) ([]byte, error) {
+	indent, err := jsonIndent(acpt)
+	if err != nil {
+		return nil, err
+	} else if indent == 0 {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", strings.Repeat(" ", indent))
+}
+
+// jsonIndent returns the number of spaces JSON should be indented by for the
+// provided Accept
+func jsonIndent(acpt *Accept) (int, error) {
+	if acpt == nil {
+		return 0, nil
+	}
+	if val, ok := acpt.AcceptParams["indent"]; ok {
+		indent, err := strconv.Atoi(val)
+		if err != nil || indent < 0 || indent > maxJSONIndent {
+			return 0, ErrInvalidAcceptParam
+		}
+		return indent, nil
+	}
+
+	for _, params := range []MediaParams{acpt.AcceptParams, acpt.AcceptExt} {
+		if val, ok := params["pretty"]; ok && val != "false" && val != "0" {
+			return 2, nil
+		}
+	}
+	return 0, nil
+}
+
+// Unmarshal parses JSON data into the provided value
+func (JSONCodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// codecEntry is a single media type registered in a Codecs, along with the
+// Codec which handles it
+type codecEntry struct {
+	mediaType MediaType
+	codec     Codec
+}
+
+// Codecs is an ordered mapping of media types to the Codecs used to marshal
+// and unmarshal them
+type Codecs struct {
+	// Fallback is the media type whose Codec represents values which the
+	// negotiated SelectiveCodec does not support, if the negotiated media
+	// range includes it. Values are not represented at all if Fallback is
+	// empty
+	Fallback MediaType
+
+	entries []codecEntry
+}
+
+// NewCodecs returns an empty Codecs
+func NewCodecs() *Codecs {
+	return &Codecs{}
+}
+
+// Register registers the Codec used to marshal and unmarshal the provided
+// media type, replacing any Codec previously registered for it
+func (c *Codecs) Register(mediaType string, codec Codec) {
+	mt := MediaType(mediaType)
+	if parsed, err := ParseMediaType(mediaType); err == nil {
+		mt = parsed.Base()
+	}
+
+	for i, entry := range c.entries {
+		if entry.mediaType == mt {
+			c.entries[i].codec = codec
+			return
+		}
+	}
+	c.entries = append(c.entries, codecEntry{mediaType: mt, codec: codec})
+}
+
+// MediaTypes returns the media types registered in the Codecs, in the order in
+// which they were registered
+func (c *Codecs) MediaTypes() []MediaType {
+	mediaTypes := make([]MediaType, 0, len(c.entries))
+	for _, entry := range c.entries {
+		mediaTypes = append(mediaTypes, entry.mediaType)
+	}
+	return mediaTypes
+}
+
+// Lookup returns the media type, and the Codec handling it, which best
+// matches the provided media range. An exact match is preferred, followed by
+// the first registered media type included in a wildcard media range. Finally,
+// a media type with a structured syntax suffix is handled by the Codec
+// registered for its suffix, eg "application/vnd.api+json" is handled by the
+// Codec registered for "application/json"
+func (c *Codecs) Lookup(mediaRange MediaType) (MediaType, Codec, bool) {
+	base := mediaRange.Base()
+	for _, entry := range c.entries {
+		if strings.EqualFold(string(entry.mediaType), string(base)) {
+			return entry.mediaType, entry.codec, true
+		}
+	}
+
+	if base.IsWildcard() {
+		for _, entry := range c.entries {
+			if base.Contains(entry.mediaType) {
+				return entry.mediaType, entry.codec, true
+			}
+		}
+		return "", nil, false
+	}
+
+	if suffix := base.Suffix(); len(suffix) > 0 {
+		if _, codec, ok := c.Lookup(MediaType(base.Type() + "/" + suffix)); ok {
+			return base, codec, true
+		}
+	}
+	return "", nil, false
+}
+
+// Media wraps a value with the Codecs used to represent it, implementing the
+// ContentNegotiator interface for values of any type
+type Media struct {
+	Value  interface{}
+	Codecs *Codecs
+}
+
+// NewMedia returns a Media which represents the provided value using the
+// provided Codecs. The value must be a pointer if it is to be unmarshalled
+func NewMedia(v interface{}, codecs *Codecs) *Media {
+	return &Media{Value: v, Codecs: codecs}
+}
+
+// codec returns the media type, and the Codec, used to represent the Media's
+// value for the provided media range, falling back to the Codecs' Fallback if
+// the matching SelectiveCodec does not support the value. The Fallback is only
+// used if the media range includes it, so that a client is never sent a media
+// type it did not accept
+func (m *Media) codec(mediaRange MediaType) (MediaType, Codec, bool) {
+	mediaType, codec, ok := m.Codecs.Lookup(mediaRange)
+	if !ok {
+		return "", nil, false
+	}
+	if sc, isSelective := codec.(SelectiveCodec); isSelective && !sc.Supports(m.Value) {
+		fallback := m.Codecs.Fallback
+		if len(fallback) == 0 || !mediaRange.containsType(fallback) {
+			return "", nil, false
+		}
+		return m.Codecs.Lookup(fallback)
+	}
+	return mediaType, codec, true
+}
+
+// ContentType returns the media type of the Codec matching the provided
+// Accept, including the parameters described by a ParamCodec, or
+// ErrNoContentType if no Codec matches
+func (m *Media) ContentType(acpt *Accept) (string, error) {
+	mediaType, codec, ok := m.codec(acpt.MediaRange)
+	if !ok {
+		return "", ErrNoContentType
+	}
+	if pc, ok := codec.(ParamCodec); ok {
+		mediaType = NewMediaType(mediaType.Type(), mediaType.SubType(), pc.Params(acpt))
+	}
+	return string(mediaType), nil
+}
+
+// MarshalMedia marshals the Media's value using the Codec matching the
+// provided Accept, or returns ErrNoContentType if no Codec matches
+func (m *Media) MarshalMedia(acpt *Accept) ([]byte, error) {
+	_, codec, ok := m.codec(acpt.MediaRange)
+	if !ok {
+		return nil, ErrNoContentType
+	}
+	return codec.Marshal(m.Value, acpt)
+}
+
+// UnmarshalMedia unmarshals the provided body into the Media's value using the
+// Codec registered for the provided content type, or returns ErrNoContentType
+// if no Codec is registered for it
+func (m *Media) UnmarshalMedia(cType string, params ContentTypeParams, body []byte) error {
+	_, codec, ok := m.Codecs.Lookup(MediaType(cType))
+	if !ok || MediaType(cType).IsWildcard() {
+		return ErrNoContentType
+	}
+	return codec.Unmarshal(body, cType, params, m.Value)
+}
+
+// RegisterCodecs registers the default value for every media type registered
+// in the provided Codecs, declaring the accept-params understood by any
+// AcceptParamCodec. Media types whose SelectiveCodec does not support the
+// default value are not registered, so that they are never negotiated
+func (r *Registry) RegisterCodecs(defaultValue interface{}, codecs *Codecs) {
+	for _, entry := range codecs.entries {
+		if sc, ok := entry.codec.(SelectiveCodec); ok && !sc.Supports(defaultValue) {
+			continue
+		}
+		r.Register(string(entry.mediaType), defaultValue)
+		if apc, ok := entry.codec.(AcceptParamCodec); ok {
+			r.DeclareAcceptParams(string(entry.mediaType), apc.AcceptParams()...)
+		}
+	}
+}
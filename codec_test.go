@@ -0,0 +1,121 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCodecModel struct {
+	Name  string `json:"name" csv:"name"`
+	Count int    `json:"count" csv:"count"`
+}
+
+func newTestCodecs() *Codecs {
+	codecs := NewCodecs()
+	codecs.Register(JSONMediaType, JSONCodec{})
+	codecs.Register(CSVMediaType, NewCSVCodec())
+	return codecs
+}
+
+func TestCodecsLookup(t *testing.T) {
+	testio := []struct {
+		mediaRange MediaType
+		expected   MediaType
+		ok         bool
+	}{
+		{"application/json", "application/json", true},
+		{"APPLICATION/JSON", "application/json", true},
+		{"text/csv;header=absent", "text/csv", true},
+		{"*/*", "application/json", true},
+		{"text/*", "text/csv", true},
+		{"application/vnd.api+json", "application/vnd.api+json", true},
+		{"application/xml", "", false},
+		{"image/*", "", false},
+	}
+
+	codecs := newTestCodecs()
+	for _, test := range testio {
+		mediaType, _, ok := codecs.Lookup(test.mediaRange)
+		assert.Equal(t, test.expected, mediaType)
+		assert.Equal(t, test.ok, ok)
+	}
+	assert.Equal(t, []MediaType{"application/json", "text/csv"}, codecs.MediaTypes())
+}
+
+func TestMedia(t *testing.T) {
+	models := []testCodecModel{{"a", 1}}
+	media := NewMedia(&models, newTestCodecs())
+
+	acpt, _ := ParseAccept("application/vnd.api+json")
+	cType, err := media.ContentType(acpt)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/vnd.api+json", cType)
+
+	data, err := media.MarshalMedia(acpt)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"name":"a","count":1}]`, string(data))
+
+	acpt, _ = ParseAccept("text/csv")
+	cType, err = media.ContentType(acpt)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv;charset=utf-8;header=present", cType)
+
+	acpt, _ = ParseAccept("application/xml")
+	_, err = media.ContentType(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+	_, err = media.MarshalMedia(acpt)
+	assert.Equal(t, ErrNoContentType, err)
+
+	err = media.UnmarshalMedia("application/json", nil, []byte(`[{"name":"b","count":2}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, []testCodecModel{{"b", 2}}, models)
+	assert.Equal(t, ErrNoContentType, media.UnmarshalMedia("application/xml", nil, nil))
+}
+
+func TestRegistryRegisterCodecs(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterCodecs(testCodecModel{}, newTestCodecs())
+
+	val, acpt, err := registry.Negotiate("text/csv;header=absent")
+	assert.Nil(t, err)
+	assert.Equal(t, testCodecModel{}, val)
+	assert.Equal(t, MediaType("text/csv"), acpt.MediaRange)
+}
+
+func TestJSONCodecIndent(t *testing.T) {
+	testio := []struct {
+		accept   string
+		expected string
+		err      error
+	}{
+		{"application/json", `{"name":"a","count":1}`, nil},
+		{"application/json;indent=0", `{"name":"a","count":1}`, nil},
+		{"application/json;indent=4", "{\n    \"name\": \"a\",\n    \"count\": 1\n}", nil},
+		{"application/json;q=1;pretty", "{\n  \"name\": \"a\",\n  \"count\": 1\n}", nil},
+		{"application/json;pretty=true", "{\n  \"name\": \"a\",\n  \"count\": 1\n}", nil},
+		{"application/json;pretty=false", `{"name":"a","count":1}`, nil},
+		{"application/json;indent=four", "", ErrInvalidAcceptParam},
+		{"application/json;indent=100", "", ErrInvalidAcceptParam},
+	}
+
+	for _, test := range testio {
+		acpt, err := ParseAccept(test.accept)
+		assert.Nil(t, err)
+		data, err := JSONCodec{}.Marshal(testCodecModel{"a", 1}, acpt)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+}
+
+func TestRegistryRegisterCodecsAcceptParams(t *testing.T) {
+	registry := NewRegistryWithPolicy(NegotiationPolicy{RejectUnknownParams: true})
+	registry.RegisterCodecs(testCodecModel{}, newTestCodecs())
+
+	_, _, err := registry.Negotiate("application/json;indent=2")
+	assert.Nil(t, err)
+	_, _, err = registry.Negotiate("text/csv;header=absent;charset=utf-8")
+	assert.Nil(t, err)
+	_, _, err = registry.Negotiate("text/csv;indent=2")
+	assert.Equal(t, ErrNoContentType, err)
+}
@@ -3,7 +3,6 @@ package negotiator
 import (
 	"io"
 	"io/ioutil"
-	"mime"
 	"net/http"
 )
 
@@ -46,15 +45,16 @@ func MarshalMedia(w io.Writer, cn ContentNegotiator, acpt *Accept) error {
 
 // UnmarshalMedia handles unmarshalling an http.Request body, using a
 // ContentNegotiator instance. An error is returned if no Content-Type header
-// was provided, if the provided Content-Type header was poorly formatted, or
-// if the body of the http.Request could not be read.
+// was provided, if the provided Content-Type header was poorly formatted, if
+// the body of the http.Request could not be read, or if the ContentNegotiator
+// fails to unmarshal it.
 func UnmarshalMedia(req *http.Request, cn ContentNegotiator) error {
 	var header string
 	if header = req.Header.Get(ContentTypeHeader); len(header) == 0 {
 		return ErrNoContentType
 	}
 
-	mediaType, params, err := mime.ParseMediaType(header)
+	contentType, err := ParseContentType(header)
 	if err != nil {
 		return err
 	}
@@ -62,6 +62,5 @@ func UnmarshalMedia(req *http.Request, cn ContentNegotiator) error {
 	if err != nil {
 		return err
 	}
-	cn.UnmarshalMedia(mediaType, params, body)
-	return nil
+	return cn.UnmarshalMedia(string(contentType.MediaType), contentType.Params, body)
 }
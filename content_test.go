@@ -67,8 +67,7 @@ func TestUnmarshalRequest(t *testing.T) {
 		// test with no content type header set
 		{"", `{"foo": "baz", "bar": 12}`, ErrNoContentType, testCN{}},
 		// test with invalid media type
-		{"white space", `{"foo": "baz", "bar": 12}`,
-			errors.New("mime: expected slash after first token"), testCN{}},
+		{"white space", `{"foo": "baz", "bar": 12}`, ErrInvalidMediaRange, testCN{}},
 	}
 
 	for _, test := range testIO {
@@ -129,10 +128,21 @@ func TestUnmarshalRequestBodyError(t *testing.T) {
 	}
 }
 
+func TestUnmarshalRequestNegotiatorError(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "http://example.com",
+		bytes.NewReader([]byte(`{"foo": `)))
+	req.Header[ContentTypeHeader] = []string{testContentNegotiatorType}
+
+	var tcn testCN
+	err := UnmarshalMedia(req, &tcn)
+	assert.NotNil(t, err)
+	assert.IsType(t, &json.SyntaxError{}, err)
+}
+
 func TestMarshalMedia(t *testing.T) {
 	testIO := []struct {
 		inp        *testCN
-		mediaRange mediaRange
+		mediaRange MediaType
 		w          io.Writer
 		err        error
 	}{
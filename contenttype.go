@@ -0,0 +1,47 @@
+package negotiator
+
+import "strings"
+
+// ContentType is the struct representation of a Content-Type header value
+type ContentType struct {
+	MediaType MediaType
+	Params    ContentTypeParams
+}
+
+// ParseContentType parses the provided Content-Type header value into a newly
+// created ContentType struct. ErrInvalidMediaRange is returned if the media
+// type is malformed or contains a wildcard, and ErrInvalidMediaParam if any of
+// its parameters are malformed
+func ParseContentType(header string) (*ContentType, error) {
+	mediaType, err := ParseMediaType(header)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType.IsWildcard() {
+		return nil, ErrInvalidMediaRange
+	}
+
+	params := ContentTypeParams(mediaType.Params())
+	if params == nil {
+		params = make(ContentTypeParams)
+	}
+	return &ContentType{MediaType: mediaType.Base(), Params: params}, nil
+}
+
+// Charset returns the lower case value of the charset parameter, or an empty
+// string if no charset was provided
+func (c *ContentType) Charset() string {
+	return strings.ToLower(c.Params["charset"])
+}
+
+// Boundary returns the value of the boundary parameter used by multipart media
+// types, or an empty string if no boundary was provided
+func (c *ContentType) Boundary() string {
+	return c.Params["boundary"]
+}
+
+// String returns the ContentType formatted as a Content-Type header value
+func (c *ContentType) String() string {
+	return string(NewMediaType(c.MediaType.Type(), c.MediaType.SubType(),
+		MediaParams(c.Params)))
+}
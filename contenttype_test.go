@@ -0,0 +1,70 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContentType(t *testing.T) {
+	testio := []struct {
+		inp       string
+		mediaType MediaType
+		params    ContentTypeParams
+		err       error
+	}{
+		{"application/json", "application/json", ContentTypeParams{}, nil},
+		{"Application/JSON; Charset=UTF-8", "application/json",
+			ContentTypeParams{"charset": "UTF-8"}, nil},
+		{`multipart/form-data; boundary="--abc;def"`, "multipart/form-data",
+			ContentTypeParams{"boundary": "--abc;def"}, nil},
+		{"application/vnd.dyn.zone+json;version=2", "application/vnd.dyn.zone+json",
+			ContentTypeParams{"version": "2"}, nil},
+		{"application/json;", "application/json", ContentTypeParams{}, nil},
+		{"text/html; charset=utf-8;", "text/html",
+			ContentTypeParams{"charset": "utf-8"}, nil},
+		{"white space", "", nil, ErrInvalidMediaRange},
+		{"application/*", "", nil, ErrInvalidMediaRange},
+		{"*/*", "", nil, ErrInvalidMediaRange},
+		{"application/json;charset", "", nil, ErrInvalidMediaParam},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			contentType, err := ParseContentType(test.inp)
+			assert.Equal(t, test.err, err)
+			if err != nil {
+				assert.Nil(t, contentType)
+				return
+			}
+			assert.Equal(t, test.mediaType, contentType.MediaType)
+			assert.Equal(t, test.params, contentType.Params)
+		})
+	}
+}
+
+func TestContentTypeAccessors(t *testing.T) {
+	testio := []struct {
+		inp      string
+		charset  string
+		boundary string
+		str      string
+	}{
+		{"application/json", "", "", "application/json"},
+		{"text/plain; charset=UTF-8", "utf-8", "", "text/plain;charset=UTF-8"},
+		{"multipart/mixed; boundary=simple", "", "simple", "multipart/mixed;boundary=simple"},
+		{`multipart/mixed; boundary="simple boundary"`, "", "simple boundary",
+			`multipart/mixed;boundary="simple boundary"`},
+		{`text/plain; title="say \"hi\""`, "", "", `text/plain;title="say \"hi\""`},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			contentType, err := ParseContentType(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.charset, contentType.Charset())
+			assert.Equal(t, test.boundary, contentType.Boundary())
+			assert.Equal(t, test.str, contentType.String())
+		})
+	}
+}
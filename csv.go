@@ -0,0 +1,385 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// CSVMediaType is the media type of RFC-4180 comma separated values
+	CSVMediaType = "text/csv"
+
+	// TSVMediaType is the media type of tab separated values
+	TSVMediaType = "text/tab-separated-values"
+)
+
+var (
+	// ErrUnsupportedType is the error returned when a value can not be
+	// represented as, or unmarshalled from, comma or tab separated values
+	ErrUnsupportedType = errors.New("Unsupported Value Type")
+
+	// ErrUnsupportedCharset is the error returned when a representation's
+	// charset parameter names a charset which is not supported
+	ErrUnsupportedCharset = errors.New("Unsupported Charset")
+
+	// ErrUnrepresentableCharacter is the error returned when a value contains
+	// a character which can not be encoded in the requested charset
+	ErrUnrepresentableCharacter = errors.New("Character Not Representable In Charset")
+)
+
+// CSVCodec is a Codec which marshals slices of structs to comma or tab
+// separated values, and unmarshals them back. Each exported field is a column,
+// named by its "csv" struct tag or its field name, and fields tagged "-" are
+// skipped. The header and charset parameters of RFC-4180 control whether a
+// header row is written or expected, and the charset of the data, which may be
+// utf-8, us-ascii or iso-8859-1
+type CSVCodec struct {
+	// Comma is the field delimiter
+	Comma rune
+}
+
+// NewCSVCodec returns a CSVCodec for text/csv
+func NewCSVCodec() *CSVCodec {
+	return &CSVCodec{Comma: ','}
+}
+
+// NewTSVCodec returns a CSVCodec for text/tab-separated-values
+func NewTSVCodec() *CSVCodec {
+	return &CSVCodec{Comma: '\t'}
+}
+
+// csvHeader returns false if the provided parameters declare the header row
+// absent. A header row is assumed present by default
+func csvHeader(params map[string]string) bool {
+	return !strings.EqualFold(params["header"], "absent")
+}
+
+// csvAcceptParams returns the accept-params of the provided Accept, which are
+// empty for a nil Accept
+func csvAcceptParams(acpt *Accept) map[string]string {
+	if acpt == nil {
+		return nil
+	}
+	return acpt.AcceptParams
+}
+
+// csvCharset returns the normalized charset declared by the provided
+// parameters, defaulting to utf-8
+func csvCharset(params map[string]string) (string, error) {
+	switch strings.ToLower(params["charset"]) {
+	case "", "utf-8", "utf8":
+		return "utf-8", nil
+	case "us-ascii", "ascii":
+		return "us-ascii", nil
+	case "iso-8859-1", "iso8859-1", "latin1":
+		return "iso-8859-1", nil
+	}
+	return "", ErrUnsupportedCharset
+}
+
+// AcceptParams returns the header and charset accept-params
+func (c *CSVCodec) AcceptParams() []string {
+	return []string{"header", "charset"}
+}
+
+// Params returns the header and charset parameters of the representation
+// rendered for the provided Accept
+func (c *CSVCodec) Params(acpt *Accept) MediaParams {
+	accepted := csvAcceptParams(acpt)
+	params := MediaParams{"header": "present"}
+	if !csvHeader(accepted) {
+		params["header"] = "absent"
+	}
+	if charset, err := csvCharset(accepted); err == nil {
+		params["charset"] = charset
+	}
+	return params
+}
+
+// Marshal renders the provided slice of structs, or pointers to structs, as
+// rows of separated values
+func (c *CSVCodec) Marshal(v interface{}, acpt *Accept) ([]byte, error) {
+	accepted := csvAcceptParams(acpt)
+	charset, err := csvCharset(accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(v))
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return nil, ErrUnsupportedType
+	}
+	fields, err := csvFields(rows.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = c.Comma
+
+	record := make([]string, len(fields))
+	if csvHeader(accepted) {
+		for i, field := range fields {
+			record[i] = field.name
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	for idx := 0; idx < rows.Len(); idx++ {
+		row := reflect.Indirect(rows.Index(idx))
+		for i, field := range fields {
+			if !row.IsValid() {
+				record[i] = ""
+				continue
+			}
+			if record[i], err = formatCSVField(row.Field(field.index)); err != nil {
+				return nil, err
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return encodeCharset(buf.Bytes(), charset)
+}
+
+// Unmarshal parses rows of separated values into the provided pointer to a
+// slice of structs, or pointers to structs. When a header row is present,
+// columns are mapped to fields by name and unknown columns are ignored.
+// Otherwise, columns are mapped to fields in order
+func (c *CSVCodec) Unmarshal(data []byte, _ string, params ContentTypeParams, v interface{}) error {
+	charset, err := csvCharset(params)
+	if err != nil {
+		return err
+	}
+	if data, err = decodeCharset(data, charset); err != nil {
+		return err
+	}
+
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return ErrUnsupportedType
+	}
+	rows := ptr.Elem()
+	elemType := rows.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+	fields, err := csvFields(elemType)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = c.Comma
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	columns := fields
+	if csvHeader(params) && len(records) > 0 {
+		columns = make([]csvField, len(records[0]))
+		for i, name := range records[0] {
+			columns[i].index = -1
+			for _, field := range fields {
+				if field.name == name {
+					columns[i] = field
+					break
+				}
+			}
+		}
+		records = records[1:]
+	}
+
+	for _, record := range records {
+		row := reflect.New(structType)
+		for i, val := range record {
+			if i >= len(columns) || columns[i].index == -1 {
+				continue
+			}
+			if err := parseCSVField(row.Elem().Field(columns[i].index), val); err != nil {
+				return err
+			}
+		}
+		if elemType.Kind() == reflect.Ptr {
+			rows = reflect.Append(rows, row)
+		} else {
+			rows = reflect.Append(rows, row.Elem())
+		}
+	}
+	ptr.Elem().Set(rows)
+	return nil
+}
+
+// csvField is a single column of separated values, mapped to a struct field
+type csvField struct {
+	name  string
+	index int
+}
+
+// csvFields returns the columns of the provided struct type, or pointer to a
+// struct type
+func csvFields(typ reflect.Type) ([]csvField, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrUnsupportedType
+	}
+
+	var fields []csvField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("csv"), ",")[0]; tag == "-" {
+			continue
+		} else if len(tag) > 0 {
+			name = tag
+		}
+		fields = append(fields, csvField{name: name, index: i})
+	}
+	return fields, nil
+}
+
+// formatCSVField formats a struct field as a single value
+func formatCSVField(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.CanInterface() {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// parseCSVField parses a single value into a struct field. An empty value
+// leaves a pointer field nil
+func parseCSVField(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if len(s) == 0 {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return ErrUnsupportedType
+	}
+	return nil
+}
+
+// encodeCharset encodes UTF-8 data in the provided charset
+func encodeCharset(data []byte, charset string) ([]byte, error) {
+	switch charset {
+	case "us-ascii", "iso-8859-1":
+		limit := rune(utf8.RuneSelf - 1)
+		if charset == "iso-8859-1" {
+			limit = 0xff
+		}
+		encoded := make([]byte, 0, len(data))
+		for _, r := range string(data) {
+			if r > limit {
+				return nil, ErrUnrepresentableCharacter
+			}
+			encoded = append(encoded, byte(r))
+		}
+		return encoded, nil
+	}
+	return data, nil
+}
+
+// decodeCharset decodes data in the provided charset to UTF-8
+func decodeCharset(data []byte, charset string) ([]byte, error) {
+	switch charset {
+	case "us-ascii", "iso-8859-1":
+		var buf bytes.Buffer
+		for _, b := range data {
+			if charset == "us-ascii" && b >= utf8.RuneSelf {
+				return nil, ErrUnrepresentableCharacter
+			}
+			buf.WriteRune(rune(b))
+		}
+		return buf.Bytes(), nil
+	}
+	return data, nil
+}
@@ -0,0 +1,113 @@
+package negotiator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCSVRow struct {
+	ID      int       `csv:"id"`
+	Name    string    `csv:"name"`
+	Score   float64   `csv:"score"`
+	Active  bool      `csv:"active"`
+	Note    *string   `csv:"note"`
+	Created time.Time `csv:"created"`
+	Secret  string    `csv:"-"`
+	hidden  string
+}
+
+func TestCSVCodecMarshal(t *testing.T) {
+	note := "ünïcode"
+	created := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []testCSVRow{
+		{1, "one", 1.5, true, &note, created, "secret", "hidden"},
+		{2, "two, three", 2, false, nil, created, "", ""},
+	}
+
+	testio := []struct {
+		codec    *CSVCodec
+		accept   string
+		expected string
+		err      error
+	}{
+		{NewCSVCodec(), "text/csv",
+			"id,name,score,active,note,created\n" +
+				"1,one,1.5,true,ünïcode,2017-01-02T03:04:05Z\n" +
+				"2,\"two, three\",2,false,,2017-01-02T03:04:05Z\n", nil},
+		{NewCSVCodec(), "text/csv;header=absent",
+			"1,one,1.5,true,ünïcode,2017-01-02T03:04:05Z\n" +
+				"2,\"two, three\",2,false,,2017-01-02T03:04:05Z\n", nil},
+		{NewTSVCodec(), "text/tab-separated-values;header=present",
+			"id\tname\tscore\tactive\tnote\tcreated\n" +
+				"1\tone\t1.5\ttrue\tünïcode\t2017-01-02T03:04:05Z\n" +
+				"2\ttwo, three\t2\tfalse\t\t2017-01-02T03:04:05Z\n", nil},
+		{NewCSVCodec(), "text/csv;header=absent;charset=iso-8859-1",
+			"1,one,1.5,true,\xfcn\xefcode,2017-01-02T03:04:05Z\n" +
+				"2,\"two, three\",2,false,,2017-01-02T03:04:05Z\n", nil},
+		{NewCSVCodec(), "text/csv;charset=us-ascii", "", ErrUnrepresentableCharacter},
+		{NewCSVCodec(), "text/csv;charset=utf-16", "", ErrUnsupportedCharset},
+	}
+
+	for _, test := range testio {
+		acpt, _ := ParseAccept(test.accept)
+		data, err := test.codec.Marshal(rows, acpt)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+
+	acpt, _ := ParseAccept("text/csv")
+	_, err := NewCSVCodec().Marshal("not a slice", acpt)
+	assert.Equal(t, ErrUnsupportedType, err)
+	_, err = NewCSVCodec().Marshal([]string{"not a struct"}, acpt)
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	data, err := NewCSVCodec().Marshal(rows[:1], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name,score,active,note,created\n"+
+		"1,one,1.5,true,ünïcode,2017-01-02T03:04:05Z\n", string(data))
+	assert.Equal(t, MediaParams{"header": "present", "charset": "utf-8"},
+		NewCSVCodec().Params(nil))
+}
+
+func TestCSVCodecUnmarshal(t *testing.T) {
+	note := "ünïcode"
+	created := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testio := []struct {
+		codec    *CSVCodec
+		params   ContentTypeParams
+		data     string
+		expected []*testCSVRow
+		err      error
+	}{
+		{NewCSVCodec(), ContentTypeParams{},
+			"name,unknown,id,note,created\none,x,1,ünïcode,2017-01-02T03:04:05Z\n",
+			[]*testCSVRow{{ID: 1, Name: "one", Note: &note, Created: created}}, nil},
+		{NewCSVCodec(), ContentTypeParams{"header": "absent"},
+			"1,one,1.5,true\n",
+			[]*testCSVRow{{ID: 1, Name: "one", Score: 1.5, Active: true}}, nil},
+		{NewTSVCodec(), ContentTypeParams{"header": "present", "charset": "iso-8859-1"},
+			"id\tnote\n2\t\xfcn\xefcode\n",
+			[]*testCSVRow{{ID: 2, Note: &note}}, nil},
+		{NewCSVCodec(), ContentTypeParams{"charset": "utf-16"}, "", nil, ErrUnsupportedCharset},
+		{NewCSVCodec(), ContentTypeParams{"charset": "us-ascii"}, "id\n\xfc\n", nil, ErrUnrepresentableCharacter},
+	}
+
+	for _, test := range testio {
+		var rows []*testCSVRow
+		err := test.codec.Unmarshal([]byte(test.data), CSVMediaType, test.params, &rows)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.expected, rows)
+	}
+
+	var rows []testCSVRow
+	err := NewCSVCodec().Unmarshal([]byte("id\nnot a number\n"), CSVMediaType, nil, &rows)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrUnsupportedType, NewCSVCodec().Unmarshal(nil, CSVMediaType, nil, rows))
+
+	err = NewCSVCodec().Unmarshal([]byte("id,name\n3,three\n"), CSVMediaType, nil, &rows)
+	assert.Nil(t, err)
+	assert.Equal(t, []testCSVRow{{ID: 3, Name: "three"}}, rows)
+}
@@ -0,0 +1,128 @@
+package negotiator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ETagHeader is the constant value for the key indicating the ETag header
+	ETagHeader = "ETag"
+
+	// IfMatchHeader is the constant value for the key indicating the If-Match
+	// header
+	IfMatchHeader = "If-Match"
+
+	// IfNoneMatchHeader is the constant value for the key indicating the
+	// If-None-Match header
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+// ETag derives an entity tag for a representation from its Content-Type and
+// body. Including the Content-Type gives each negotiated variant of a resource
+// a distinct entity tag, even when their bodies are identical. A weak entity
+// tag is returned if weak is true
+func ETag(contentType string, body []byte, weak bool) string {
+	hash := sha256.New()
+	hash.Write([]byte(contentType))
+	hash.Write([]byte{0})
+	hash.Write(body)
+
+	tag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// isWeak returns true if the provided entity tag is weak
+func isWeak(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// etagsMatch compares two entity tags. Under strong comparison both must be
+// strong and identical, while weak comparison ignores their weakness
+func etagsMatch(a, b string, strong bool) bool {
+	if strong && (isWeak(a) || isWeak(b)) {
+		return false
+	}
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// matchesETag returns true if the provided If-Match or If-None-Match header
+// value is "*", or lists an entity tag matching etag
+func matchesETag(header, etag string, strong bool) bool {
+	for _, tag := range splitQuoted(header, ',') {
+		tag = strings.TrimSpace(tag)
+		if tag == WildCard || etagsMatch(tag, etag, strong) {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluatePreconditions evaluates the If-Match and If-None-Match headers of
+// the provided request against the entity tag of the selected representation,
+// as defined by RFC-7232. http.StatusPreconditionFailed is returned if
+// If-Match does not strongly match the entity tag, or if If-None-Match matches
+// it on a request other than GET or HEAD. http.StatusNotModified is returned
+// if If-None-Match matches it on a GET or HEAD request. Otherwise 0 is
+// returned, and the request should proceed
+func EvaluatePreconditions(req *http.Request, etag string) int {
+	if header := req.Header.Get(IfMatchHeader); len(header) > 0 {
+		if !matchesETag(header, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if header := req.Header.Get(IfNoneMatchHeader); len(header) > 0 {
+		if matchesETag(header, etag, false) {
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	}
+	return 0
+}
+
+// WriteConditional marshals the ContentNegotiator for the provided Accept and
+// derives the representation's entity tag from its Content-Type and body, as
+// described by ETag. The request's preconditions are then evaluated against
+// the entity tag. A 304 Not Modified response is written without a body, a 412
+// Precondition Failed response is written as a Problem, and otherwise the
+// representation is written with a 200 OK status. The ETag header is set on
+// all but 412 responses, and Accept is added to the Vary header of all of them
+func WriteConditional(w http.ResponseWriter, req *http.Request, cn ContentNegotiator, acpt *Accept, weak bool) error {
+	contentType, err := cn.ContentType(acpt)
+	if err != nil {
+		return err
+	}
+	body, err := cn.MarshalMedia(acpt)
+	if err != nil {
+		return err
+	}
+	etag := ETag(contentType, body, weak)
+
+	AddVary(w.Header(), AcceptHeaderKey)
+	switch EvaluatePreconditions(req, etag) {
+	case http.StatusPreconditionFailed:
+		return WriteProblem(w, req, NewProblem(http.StatusPreconditionFailed,
+			"The representation does not satisfy the request's preconditions."))
+	case http.StatusNotModified:
+		w.Header().Set(ETagHeader, etag)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set(ETagHeader, etag)
+	w.Header().Set(ContentTypeHeader, contentType)
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead {
+		return nil
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,101 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	json := ETag("application/json", []byte("{}"), false)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, json)
+	assert.Equal(t, json, ETag("application/json", []byte("{}"), false))
+	assert.Equal(t, "W/"+json, ETag("application/json", []byte("{}"), true))
+	assert.NotEqual(t, json, ETag("application/xml", []byte("{}"), false))
+	assert.NotEqual(t, json, ETag("application/json", []byte("[]"), false))
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	etag := `"abc"`
+	testio := []struct {
+		method      string
+		ifMatch     string
+		ifNoneMatch string
+		etag        string
+		expected    int
+	}{
+		{"GET", "", "", etag, 0},
+		{"GET", "", `"abc"`, etag, http.StatusNotModified},
+		{"GET", "", `W/"abc"`, etag, http.StatusNotModified},
+		{"HEAD", "", `"xyz", "abc"`, etag, http.StatusNotModified},
+		{"GET", "", "*", etag, http.StatusNotModified},
+		{"GET", "", `"xyz"`, etag, 0},
+		{"PUT", "", "*", etag, http.StatusPreconditionFailed},
+		{"PUT", `"abc"`, "", etag, 0},
+		{"PUT", "*", "", etag, 0},
+		{"PUT", `"xyz", "abc"`, "", etag, 0},
+		{"PUT", `"xyz"`, "", etag, http.StatusPreconditionFailed},
+		{"PUT", `W/"abc"`, "", etag, http.StatusPreconditionFailed},
+		{"PUT", `"abc"`, "", `W/"abc"`, http.StatusPreconditionFailed},
+		{"GET", `"abc"`, `"abc"`, etag, http.StatusNotModified},
+	}
+
+	for _, test := range testio {
+		req := httptest.NewRequest(test.method, "/", nil)
+		if len(test.ifMatch) > 0 {
+			req.Header.Set(IfMatchHeader, test.ifMatch)
+		}
+		if len(test.ifNoneMatch) > 0 {
+			req.Header.Set(IfNoneMatchHeader, test.ifNoneMatch)
+		}
+		assert.Equal(t, test.expected, EvaluatePreconditions(req, test.etag))
+	}
+}
+
+func TestWriteConditional(t *testing.T) {
+	acpt, _ := ParseAccept(testContentNegotiatorType)
+	cn := newTcn("foo", 1)
+	body, _ := cn.MarshalMedia(acpt)
+	etag := ETag(testContentNegotiatorType, body, false)
+
+	testio := []struct {
+		method      string
+		ifNoneMatch string
+		ifMatch     string
+		status      int
+		etag        string
+		body        string
+	}{
+		{"GET", "", "", http.StatusOK, etag, string(body)},
+		{"HEAD", "", "", http.StatusOK, etag, ""},
+		{"GET", etag, "", http.StatusNotModified, etag, ""},
+		{"GET", `"stale"`, "", http.StatusOK, etag, string(body)},
+		{"PUT", "", `"stale"`, http.StatusPreconditionFailed, "", ""},
+	}
+
+	for _, test := range testio {
+		req := httptest.NewRequest(test.method, "/", nil)
+		req.Header.Set(AcceptHeaderKey, "application/json")
+		if len(test.ifNoneMatch) > 0 {
+			req.Header.Set(IfNoneMatchHeader, test.ifNoneMatch)
+		}
+		if len(test.ifMatch) > 0 {
+			req.Header.Set(IfMatchHeader, test.ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		assert.Nil(t, WriteConditional(w, req, cn, acpt, false))
+		assert.Equal(t, test.status, w.Code)
+		assert.Equal(t, test.etag, w.Header().Get(ETagHeader))
+		if test.status != http.StatusPreconditionFailed {
+			assert.Equal(t, test.body, w.Body.String())
+		}
+	}
+
+	badAcpt, _ := ParseAccept("application/xml")
+	w := httptest.NewRecorder()
+	assert.Equal(t, errInvalidMediaType,
+		WriteConditional(w, httptest.NewRequest("GET", "/", nil), cn, badAcpt, true))
+}
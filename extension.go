@@ -0,0 +1,152 @@
+package negotiator
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"strings"
+)
+
+// ContentDispositionHeader is the constant value for the key indicating the
+// Content-Disposition header
+const ContentDispositionHeader = "Content-Disposition"
+
+var (
+	// ErrUnknownExtension is the error returned when a file extension has no
+	// known media type
+	ErrUnknownExtension = errors.New("Unknown File Extension")
+)
+
+// preferredExtensions are the preferred file extensions of media types which
+// the mime package associates with several extensions
+var preferredExtensions = map[MediaType]string{
+	"application/json":       ".json",
+	"application/xml":        ".xml",
+	"application/javascript": ".js",
+	"image/jpeg":             ".jpg",
+	"image/svg+xml":          ".svg",
+	"image/tiff":             ".tiff",
+	"text/html":              ".html",
+	"text/plain":             ".txt",
+	"text/javascript":        ".js",
+}
+
+// TypeByExtension returns the media type associated with the provided file
+// extension, with or without a leading ".", using the mime package's table
+// along with any mime.types files it has loaded. The returned media type has
+// no parameters. ErrUnknownExtension is returned if the extension is unknown
+func TypeByExtension(ext string) (MediaType, error) {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	typ := mime.TypeByExtension(ext)
+	if len(typ) == 0 {
+		return "", ErrUnknownExtension
+	}
+
+	mediaType, err := ParseMediaType(typ)
+	if err != nil {
+		return "", err
+	}
+	return mediaType.Base(), nil
+}
+
+// LoadMimeTypes reads a mime.types file, such as /etc/mime.types, adding the
+// extensions it defines to the mime package's table. Each line of the file
+// contains a media type followed by its whitespace separated extensions, and
+// lines beginning with "#" are ignored
+func LoadMimeTypes(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ReadMimeTypes(f)
+}
+
+// ReadMimeTypes reads the contents of a mime.types file from the provided
+// io.Reader, adding the extensions it defines to the mime package's table
+func ReadMimeTypes(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if _, err := ParseMediaType(fields[0]); err != nil {
+			return err
+		}
+		for _, ext := range fields[1:] {
+			if strings.HasPrefix(ext, "#") {
+				break
+			}
+			if err := mime.AddExtensionType("."+ext, fields[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Extension returns the preferred file extension, including its leading ".",
+// for the media type. Extensions which match the media type's subtype or
+// structured syntax suffix are preferred, eg ".json" for
+// "application/vnd.api+json". An empty string is returned if the media type has
+// no known extension
+func (m MediaType) Extension() string {
+	base := MediaType(strings.ToLower(string(m.Base())))
+	if ext, ok := preferredExtensions[base]; ok {
+		return ext
+	}
+
+	exts, _ := mime.ExtensionsByType(string(base))
+	for _, ext := range exts {
+		if ext == "."+base.SubType() {
+			return ext
+		}
+	}
+	if len(exts) > 0 {
+		return exts[0]
+	}
+
+	if suffix := base.Suffix(); len(suffix) > 0 {
+		return MediaType(base.Type() + "/" + suffix).Extension()
+	}
+	return ""
+}
+
+// ContentDisposition returns a Content-Disposition header value presenting a
+// response of the provided media type as an attachment, named by appending the
+// media type's preferred extension to the provided filename
+func ContentDisposition(filename string, mediaType MediaType) string {
+	return mime.FormatMediaType("attachment",
+		map[string]string{"filename": filename + mediaType.Extension()})
+}
+
+// RegisterExtension registers the default value for the media type associated
+// with the provided file extension. ErrUnknownExtension is returned if the
+// extension is unknown
+func (r *Registry) RegisterExtension(ext string, defaultValue interface{}) error {
+	mediaType, err := TypeByExtension(ext)
+	if err != nil {
+		return err
+	}
+	r.Register(string(mediaType), defaultValue)
+	return nil
+}
+
+// RegisterExtension maps the provided file extension, with or without a
+// leading ".", to the media type associated with it. ErrUnknownExtension is
+// returned if the extension is unknown
+func (f *FormatOverride) RegisterExtension(ext string) error {
+	mediaType, err := TypeByExtension(ext)
+	if err != nil {
+		return err
+	}
+	f.Register(ext, mediaType)
+	return nil
+}
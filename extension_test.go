@@ -0,0 +1,88 @@
+package negotiator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMimeTypes = `# test mime.types
+application/vnd.negotiator.test+json	negtest negtst # trailing comment
+text/x-negotiator	negtxt
+`
+
+func TestTypeByExtension(t *testing.T) {
+	assert.Nil(t, ReadMimeTypes(strings.NewReader(testMimeTypes)))
+
+	testio := []struct {
+		ext       string
+		mediaType MediaType
+		err       error
+	}{
+		{".json", "application/json", nil},
+		{"json", "application/json", nil},
+		{".html", "text/html", nil},
+		{"negtest", "application/vnd.negotiator.test+json", nil},
+		{".negtxt", "text/x-negotiator", nil},
+		{".negunknown", "", ErrUnknownExtension},
+	}
+
+	for _, test := range testio {
+		t.Run(test.ext, func(t *testing.T) {
+			mediaType, err := TypeByExtension(test.ext)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.mediaType, mediaType)
+		})
+	}
+}
+
+func TestReadMimeTypesInvalid(t *testing.T) {
+	err := ReadMimeTypes(strings.NewReader("application negbad\n"))
+	assert.Equal(t, ErrInvalidMediaRange, err)
+}
+
+func TestMediaTypeExtension(t *testing.T) {
+	assert.Nil(t, ReadMimeTypes(strings.NewReader(testMimeTypes)))
+
+	testio := []struct {
+		mediaType MediaType
+		ext       string
+	}{
+		{"application/json", ".json"},
+		{"application/json;charset=utf-8", ".json"},
+		{"image/jpeg", ".jpg"},
+		{"text/html", ".html"},
+		{"image/png", ".png"},
+		{"application/vnd.negotiator.test+json", ".negtest"},
+		{"application/vnd.dyn.zone+json", ".json"},
+		{"application/vnd.dyn.zone+xml", ".xml"},
+		{"application/vnd.negotiator.unknown", ""},
+	}
+
+	for _, test := range testio {
+		t.Run(test.mediaType.String(), func(t *testing.T) {
+			assert.Equal(t, test.ext, test.mediaType.Extension())
+		})
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	assert.Equal(t, "attachment; filename=report.json",
+		ContentDisposition("report", "application/json"))
+	assert.Equal(t, `attachment; filename="monthly report.html"`,
+		ContentDisposition("monthly report", "text/html"))
+}
+
+func TestRegistryRegisterExtension(t *testing.T) {
+	testReg := NewRegistry()
+	assert.Nil(t, testReg.RegisterExtension(".json", testGeneric{}))
+	assert.Nil(t, testReg.RegisterExtension("html", testSpecific{}))
+	assert.Equal(t, ErrUnknownExtension, testReg.RegisterExtension(".negunknown", testSpecific{}))
+	assert.Equal(t, []MediaType{"application/json", "text/html"}, testReg.MediaTypes())
+
+	f := &FormatOverride{}
+	assert.Nil(t, f.RegisterExtension(".svg"))
+	assert.Equal(t, ErrUnknownExtension, f.RegisterExtension("negunknown"))
+	assert.Equal(t, map[string]MediaType{"svg": "image/svg+xml"}, f.Formats)
+}
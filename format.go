@@ -0,0 +1,252 @@
+package negotiator
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FormatPrecedence controls whether a format override or the Accept header is
+// preferred when both are present on a request
+type FormatPrecedence int
+
+const (
+	// OverrideFirst always negotiates the media type of a format override,
+	// ignoring the Accept header
+	OverrideFirst FormatPrecedence = iota
+
+	// AcceptFirst only negotiates the media type of a format override if the
+	// Accept header is missing, or none of its media ranges are available
+	AcceptFirst
+)
+
+// DefaultFormatParam is the default query parameter used to override the
+// negotiated format of a response (e.g /messages?format=csv)
+const DefaultFormatParam = "format"
+
+// formatContextKey is the request context key under which a format override
+// detected by FormatOverride.Middleware is stored
+type formatContextKey struct{}
+
+// FormatOverride maps URL path extensions (e.g /messages.json) and the value
+// of a query parameter (e.g /messages?format=json) to media types, allowing
+// clients that can not set an Accept header to choose a representation
+type FormatOverride struct {
+	// Formats maps a format name, without a leading ".", to its media type
+	Formats map[string]MediaType
+
+	// QueryParam is the name of the query parameter used to select a format.
+	// The query parameter is ignored if QueryParam is empty
+	QueryParam string
+
+	// Extensions enables selecting a format by URL path extension
+	Extensions bool
+
+	// Precedence controls whether the format override or the Accept header
+	// is preferred when both are present
+	Precedence FormatPrecedence
+}
+
+// NewFormatOverride returns a FormatOverride which honors both URL path
+// extensions and the DefaultFormatParam query parameter, in preference to the
+// Accept header, for the json, xml, csv, html, and txt formats
+func NewFormatOverride() *FormatOverride {
+	return &FormatOverride{Formats: map[string]MediaType{
+		"json": "application/json",
+		"xml":  "application/xml",
+		"csv":  "text/csv",
+		"html": "text/html",
+		"txt":  "text/plain",
+	},
+		QueryParam: DefaultFormatParam,
+		Extensions: true,
+		Precedence: OverrideFirst}
+}
+
+// Register maps the provided format name, with or without a leading ".", to a
+// media type
+func (f *FormatOverride) Register(format string, mediaType MediaType) {
+	if f.Formats == nil {
+		f.Formats = make(map[string]MediaType)
+	}
+	f.Formats[strings.ToLower(strings.TrimPrefix(format, "."))] = mediaType
+}
+
+// lookup returns the media type registered for the provided format name
+func (f *FormatOverride) lookup(format string) (MediaType, bool) {
+	mediaType, ok := f.Formats[strings.ToLower(format)]
+	return mediaType, ok
+}
+
+// extension returns the format name of the provided URL path's extension, if
+// it has a registered extension
+func (f *FormatOverride) extension(urlPath string) (string, bool) {
+	if !f.Extensions {
+		return "", false
+	}
+
+	ext := strings.TrimPrefix(path.Ext(urlPath), ".")
+	if _, ok := f.lookup(ext); !ok || len(ext) == 0 {
+		return "", false
+	}
+	return ext, true
+}
+
+// Format returns the media type selected by the request's format query
+// parameter or URL path extension, in that order. An unregistered query
+// parameter value results in an empty media type being returned alongside
+// true, indicating a format was requested which is not available
+func (f *FormatOverride) Format(req *http.Request) (MediaType, bool) {
+	if mediaType, ok := req.Context().Value(formatContextKey{}).(MediaType); ok {
+		return mediaType, true
+	}
+
+	if len(f.QueryParam) > 0 {
+		if format := req.URL.Query().Get(f.QueryParam); len(format) > 0 {
+			mediaType, _ := f.lookup(format)
+			return mediaType, true
+		}
+	}
+
+	if ext, ok := f.extension(req.URL.Path); ok {
+		mediaType, _ := f.lookup(ext)
+		return mediaType, true
+	}
+	return "", false
+}
+
+// Middleware returns an http.Handler which detects the format override of each
+// request before calling next. A registered URL path extension is removed from
+// the request's path, allowing /messages.json to be routed as /messages
+func (f *FormatOverride) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mediaType, ok := f.Format(req)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), formatContextKey{}, mediaType)
+		req = req.WithContext(ctx)
+		if ext, ok := f.extension(req.URL.Path); ok {
+			u := *req.URL
+			u.Path = strings.TrimSuffix(u.Path, "."+ext)
+			u.RawPath = ""
+			req.URL = &u
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// AlternateURI implements AlternateURIFunc, returning the request's URI with
+// its format query parameter set to the format name registered for the
+// provided media type. A format registered for the media type including its
+// parameters is preferred, followed by one registered for the media type
+// without them (e.g application/json;version=2 uses the format registered for
+// application/json). If several format names match equally, the
+// alphabetically first is used. The request's URI is returned unchanged if no
+// format is registered for the media type, or the query parameter is disabled
+func (f *FormatOverride) AlternateURI(req *http.Request, mediaType MediaType) string {
+	u := &url.URL{Path: req.URL.Path, RawQuery: req.URL.RawQuery}
+	if len(f.QueryParam) == 0 {
+		return u.String()
+	}
+
+	formats := f.formatsFor(mediaType, false)
+	if len(formats) == 0 {
+		formats = f.formatsFor(mediaType, true)
+	}
+	if len(formats) == 0 {
+		return u.String()
+	}
+	sort.Strings(formats)
+
+	query := u.Query()
+	query.Set(f.QueryParam, formats[0])
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// formatsFor returns the format names registered for the provided media type,
+// comparing only the media types without their parameters if base is true
+func (f *FormatOverride) formatsFor(mediaType MediaType, base bool) []string {
+	mediaType = normalizeMediaType(mediaType, base)
+	var formats []string
+	for format, registered := range f.Formats {
+		if normalizeMediaType(registered, base) == mediaType {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// normalizeMediaType returns the normalized form of the provided media type,
+// without its parameters if base is true. Media types which can not be parsed
+// are returned unchanged
+func normalizeMediaType(mediaType MediaType, base bool) MediaType {
+	if parsed, err := ParseMediaType(string(mediaType)); err == nil {
+		mediaType = parsed
+	}
+	if base {
+		return mediaType.Base()
+	}
+	return mediaType
+}
+
+// SetFormatOverride configures the FormatOverride consulted by
+// NegotiateRequest. A nil FormatOverride disables format overrides
+func (r *Registry) SetFormatOverride(f *FormatOverride) {
+	r.formats = f
+}
+
+// NegotiateRequest negotiates the representation of the provided request,
+// honoring the Registry's FormatOverride, if one is set, according to its
+// FormatPrecedence. A request without an Accept header is treated as
+// accepting any media type
+func (r *Registry) NegotiateRequest(req *http.Request) (interface{}, *Accept, error) {
+	val, acpt, _, err := r.negotiateRequest(req)
+	return val, acpt, err
+}
+
+// negotiateRequest implements NegotiateRequest, additionally returning the
+// names of the request headers consulted while negotiating
+func (r *Registry) negotiateRequest(req *http.Request) (interface{}, *Accept, []string, error) {
+	header := req.Header.Get(AcceptHeaderKey)
+	consulted := []string{AcceptHeaderKey}
+	if r.formats == nil {
+		val, acpt, err := r.Negotiate(acceptOrAny(header))
+		return val, acpt, consulted, err
+	}
+
+	override, ok := r.formats.Format(req)
+	if !ok {
+		val, acpt, err := r.Negotiate(acceptOrAny(header))
+		return val, acpt, consulted, err
+	}
+
+	if r.formats.Precedence != AcceptFirst {
+		consulted = nil
+	} else if len(header) > 0 {
+		if val, acpt, err := r.Negotiate(header); err == nil {
+			return val, acpt, consulted, nil
+		}
+	}
+
+	if len(override) == 0 {
+		return nil, nil, consulted, ErrNoContentType
+	}
+	val, acpt, err := r.Negotiate(string(override))
+	return val, acpt, consulted, err
+}
+
+// acceptOrAny returns the provided Accept header, or a media range accepting
+// any media type if the header is empty
+func acceptOrAny(header string) string {
+	if len(header) == 0 {
+		return WildCard + "/" + WildCard
+	}
+	return header
+}
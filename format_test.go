@@ -0,0 +1,135 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOverrideFormat(t *testing.T) {
+	testio := []struct {
+		url       string
+		mediaType MediaType
+		ok        bool
+	}{
+		{"http://example.com/messages", "", false},
+		{"http://example.com/messages.json", "application/json", true},
+		{"http://example.com/messages.JSON", "application/json", true},
+		{"http://example.com/messages.bogus", "", false},
+		{"http://example.com/messages?format=csv", "text/csv", true},
+		{"http://example.com/messages.json?format=xml", "application/xml", true},
+		{"http://example.com/messages?format=bogus", "", true},
+		{"http://example.com/v1.2/messages", "", false},
+	}
+
+	f := NewFormatOverride()
+	for _, test := range testio {
+		t.Run(test.url, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.url, nil)
+			mediaType, ok := f.Format(req)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.mediaType, mediaType)
+		})
+	}
+}
+
+func TestFormatOverrideDisabled(t *testing.T) {
+	f := &FormatOverride{}
+	f.Register(".json", "application/json")
+
+	req := httptest.NewRequest("GET", "http://example.com/messages.json?format=json", nil)
+	_, ok := f.Format(req)
+	assert.False(t, ok)
+}
+
+func TestRegistryNegotiateRequest(t *testing.T) {
+	testio := []struct {
+		url        string
+		accept     string
+		precedence FormatPrecedence
+		expected   interface{}
+		err        error
+	}{
+		{"http://example.com/messages", "text/csv", OverrideFirst, testSpecific{}, nil},
+		{"http://example.com/messages", "", OverrideFirst, testGeneric{}, nil},
+		{"http://example.com/messages.csv", "application/json", OverrideFirst, testSpecific{}, nil},
+		{"http://example.com/messages?format=csv", "application/json", OverrideFirst, testSpecific{}, nil},
+		{"http://example.com/messages?format=html", "application/json", OverrideFirst, nil, ErrNoContentType},
+		{"http://example.com/messages?format=bogus", "application/json", OverrideFirst, nil, ErrNoContentType},
+		{"http://example.com/messages.csv", "application/json", AcceptFirst, testGeneric{}, nil},
+		{"http://example.com/messages.csv", "image/png", AcceptFirst, testSpecific{}, nil},
+		{"http://example.com/messages.csv", "", AcceptFirst, testSpecific{}, nil},
+	}
+
+	testReg := NewRegistry()
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("text/csv", testSpecific{})
+
+	for _, test := range testio {
+		t.Run(test.url+" "+test.accept, func(t *testing.T) {
+			f := NewFormatOverride()
+			f.Precedence = test.precedence
+			testReg.SetFormatOverride(f)
+
+			req := httptest.NewRequest("GET", test.url, nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			i, _, err := testReg.NegotiateRequest(req)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, i)
+		})
+	}
+}
+
+func TestFormatOverrideMiddleware(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("text/csv", testSpecific{})
+
+	f := NewFormatOverride()
+	testReg.SetFormatOverride(f)
+
+	var path string
+	var negotiated interface{}
+	handler := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		negotiated, _, _ = testReg.NegotiateRequest(req)
+	}))
+
+	testio := []struct {
+		url      string
+		path     string
+		expected interface{}
+	}{
+		{"http://example.com/messages.csv", "/messages", testSpecific{}},
+		{"http://example.com/messages.json", "/messages", testGeneric{}},
+		{"http://example.com/messages.tar", "/messages.tar", testGeneric{}},
+		{"http://example.com/messages", "/messages", testGeneric{}},
+	}
+
+	for _, test := range testio {
+		t.Run(test.url, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.url, nil)
+			req.Header.Set(AcceptHeaderKey, "application/json")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, test.path, path)
+			assert.Equal(t, test.expected, negotiated)
+		})
+	}
+}
+
+func TestFormatOverrideAlternateURI(t *testing.T) {
+	f := NewFormatOverride()
+	f.Register("jsn", "application/json")
+	req := httptest.NewRequest("GET", "http://example.com/messages?page=2", nil)
+
+	assert.Equal(t, "/messages?format=jsn&page=2", f.AlternateURI(req, "application/json"))
+	assert.Equal(t, "/messages?format=csv&page=2", f.AlternateURI(req, "text/csv"))
+	assert.Equal(t, "/messages?page=2", f.AlternateURI(req, "image/png"))
+
+	f.Register("v2", "application/json; version=2")
+	assert.Equal(t, "/messages?format=v2&page=2", f.AlternateURI(req, "application/json;version=2"))
+	assert.Equal(t, "/messages?format=jsn&page=2", f.AlternateURI(req, "application/json;version=1"))
+	assert.Equal(t, "/messages?format=jsn&page=2", f.AlternateURI(req, "Application/JSON"))
+}
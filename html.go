@@ -0,0 +1,77 @@
+package negotiator
+
+import (
+	"bytes"
+	"html/template"
+)
+
+const (
+	// HTMLMediaType is the media type of an HTML document
+	HTMLMediaType = "text/html"
+
+	// XHTMLMediaType is the media type of an XHTML document
+	XHTMLMediaType = "application/xhtml+xml"
+)
+
+// TemplateData is the data an HTMLCodec's template is executed with
+type TemplateData struct {
+	// Model is the value being represented
+	Model interface{}
+
+	// Accept is the media range the representation was negotiated for
+	Accept *Accept
+}
+
+// HTMLCodec is a Codec which renders values as human readable pages for
+// browser clients by executing an html/template with TemplateData. HTML pages
+// can not be unmarshalled
+type HTMLCodec struct {
+	// Template is the template executed to render a page
+	Template *template.Template
+
+	// Name is the name of the template to execute. Template itself is
+	// executed if Name is empty
+	Name string
+}
+
+// NewHTMLCodec returns an HTMLCodec which executes the named template, or the
+// provided template itself if name is empty
+func NewHTMLCodec(tmpl *template.Template, name string) *HTMLCodec {
+	return &HTMLCodec{Template: tmpl, Name: name}
+}
+
+// Params returns the utf-8 charset of the rendered page
+func (c *HTMLCodec) Params(*Accept) MediaParams {
+	return MediaParams{"charset": "utf-8"}
+}
+
+// Marshal renders the provided value by executing the HTMLCodec's template
+func (c *HTMLCodec) Marshal(v interface{}, acpt *Accept) ([]byte, error) {
+	var buf bytes.Buffer
+	data := TemplateData{Model: v, Accept: acpt}
+
+	var err error
+	if len(c.Name) > 0 {
+		err = c.Template.ExecuteTemplate(&buf, c.Name, data)
+	} else {
+		err = c.Template.Execute(&buf, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal returns ErrUnsupportedType, as HTML pages can not be unmarshalled
+func (c *HTMLCodec) Unmarshal([]byte, string, ContentTypeParams, interface{}) error {
+	return ErrUnsupportedType
+}
+
+// RegisterTemplate registers an HTMLCodec executing the named template, or
+// the provided template itself if name is empty, for both text/html and
+// application/xhtml+xml
+func (c *Codecs) RegisterTemplate(tmpl *template.Template, name string) {
+	codec := NewHTMLCodec(tmpl, name)
+	c.Register(HTMLMediaType, codec)
+	c.Register(XHTMLMediaType, codec)
+}
@@ -0,0 +1,60 @@
+package negotiator
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testTemplates = template.Must(template.New("page").Parse(
+	`<p>{{.Model.Name}} x{{.Model.Count}} as {{.Accept.MediaRange}}</p>`))
+
+func TestHTMLCodec(t *testing.T) {
+	template.Must(testTemplates.New("fails").Parse(`{{.Model.Missing}}`))
+
+	codecs := NewCodecs()
+	codecs.Register(JSONMediaType, JSONCodec{})
+	codecs.RegisterTemplate(testTemplates, "page")
+
+	registry := NewRegistry()
+	registry.RegisterCodecs(testCodecModel{}, codecs)
+
+	testio := []struct {
+		accept   string
+		cType    string
+		expected string
+	}{
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+			"text/html;charset=utf-8", "<p>&lt;b&gt; x2 as text/html</p>"},
+		{"application/xhtml+xml", "application/xhtml+xml;charset=utf-8",
+			"<p>&lt;b&gt; x2 as application/xhtml&#43;xml</p>"},
+		{"application/json", "application/json", `{"name":"\u003cb\u003e","count":2}`},
+	}
+
+	for _, test := range testio {
+		_, acpt, err := registry.Negotiate(test.accept)
+		assert.Nil(t, err)
+
+		media := NewMedia(testCodecModel{"<b>", 2}, codecs)
+		cType, err := media.ContentType(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.cType, cType)
+
+		data, err := media.MarshalMedia(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+
+	acpt, _ := ParseAccept(HTMLMediaType)
+	_, err := NewHTMLCodec(testTemplates, "fails").Marshal(testCodecModel{}, acpt)
+	assert.NotNil(t, err)
+
+	page := template.Must(template.New("").Parse(`{{.Model}}`))
+	data, err := NewHTMLCodec(page, "").Marshal("plain", acpt)
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", string(data))
+
+	media := NewMedia(&testCodecModel{}, codecs)
+	assert.Equal(t, ErrUnsupportedType, media.UnmarshalMedia(HTMLMediaType, nil, nil))
+}
@@ -0,0 +1,577 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// HALMediaType is the media type of a Hypertext Application Language
+	// document
+	HALMediaType = "application/hal+json"
+
+	// JSONAPIMediaType is the media type of a JSON:API document
+	JSONAPIMediaType = "application/vnd.api+json"
+
+	// SirenMediaType is the media type of a Siren entity
+	SirenMediaType = "application/vnd.siren+json"
+)
+
+// jsonAPIParams are the only media type parameters JSON:API permits on its
+// media type
+var jsonAPIParams = map[string]bool{"ext": true, "profile": true}
+
+// Link is a hypermedia link from a Resource to a related resource
+type Link struct {
+	Rel   string
+	Href  string
+	Title string
+	Type  MediaType
+}
+
+// Resource is a format agnostic hypermedia resource, from which HAL, JSON:API
+// and Siren representations are built
+type Resource struct {
+	// Type is the type of the resource, rendered as a JSON:API type or a Siren
+	// class
+	Type string
+
+	// ID identifies the resource among resources of its Type
+	ID string
+
+	// Attributes is the state of the resource, which must marshal to a JSON
+	// object
+	Attributes interface{}
+
+	// Links are the resource's links to related resources
+	Links []Link
+
+	// Embedded maps relations to the related resources embedded within the
+	// resource's representation
+	Embedded map[string][]*Resource
+}
+
+// NewResource returns a Resource with the provided type, id and attributes
+func NewResource(typ, id string, attributes interface{}) *Resource {
+	return &Resource{Type: typ, ID: id, Attributes: attributes}
+}
+
+// AddLink adds a link with the provided relation to the Resource
+func (r *Resource) AddLink(rel, href string) *Resource {
+	r.Links = append(r.Links, Link{Rel: rel, Href: href})
+	return r
+}
+
+// Embed embeds the provided related resources within the Resource
+func (r *Resource) Embed(rel string, resources ...*Resource) *Resource {
+	if r.Embedded == nil {
+		r.Embedded = make(map[string][]*Resource)
+	}
+	r.Embedded[rel] = append(r.Embedded[rel], resources...)
+	return r
+}
+
+// embeddedRels returns the relations of the Resource's embedded resources in
+// sorted order
+func (r *Resource) embeddedRels() []string {
+	rels := make([]string, 0, len(r.Embedded))
+	for rel := range r.Embedded {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	return rels
+}
+
+// attributes returns the Resource's attributes marshalled as JSON, or nil if
+// it has none. ErrUnsupportedType is returned if they are not a JSON object
+func (r *Resource) attributes() (json.RawMessage, error) {
+	if r.Attributes == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(r.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return nil, ErrUnsupportedType
+	}
+	return data, nil
+}
+
+// setAttributes unmarshals JSON attributes into the Resource's Attributes if
+// they hold a pointer, or into a map otherwise
+func (r *Resource) setAttributes(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if r.Attributes != nil {
+		return json.Unmarshal(data, r.Attributes)
+	}
+	attributes := make(map[string]interface{})
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return err
+	}
+	r.Attributes = attributes
+	return nil
+}
+
+// asResource returns the provided value as a *Resource
+func asResource(v interface{}) (*Resource, bool) {
+	switch r := v.(type) {
+	case *Resource:
+		return r, r != nil
+	case Resource:
+		return &r, true
+	}
+	return nil, false
+}
+
+// supportsResource implements SelectiveCodec for the hypermedia codecs
+func supportsResource(v interface{}) bool {
+	_, ok := asResource(v)
+	return ok
+}
+
+// marshalResource marshals the provided value, which must be a Resource, using
+// the provided builder
+func marshalResource(v interface{}, build func(*Resource) (interface{}, error)) ([]byte, error) {
+	r, ok := asResource(v)
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	doc, err := build(r)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// unmarshalResource unmarshals data into the provided value, which must be a
+// *Resource, using the provided parser
+func unmarshalResource(data []byte, v interface{}, parse func([]byte, *Resource) error) error {
+	r, ok := v.(*Resource)
+	if !ok || r == nil {
+		return ErrUnsupportedType
+	}
+	return parse(data, r)
+}
+
+// halLink is a HAL link object
+type halLink struct {
+	Href  string    `json:"href"`
+	Title string    `json:"title,omitempty"`
+	Type  MediaType `json:"type,omitempty"`
+}
+
+// HALCodec is a SelectiveCodec which represents a Resource as an
+// application/hal+json document. The Resource's attributes are rendered as
+// members of the document, alongside its _links and _embedded resources
+type HALCodec struct{}
+
+// Supports returns true if the provided value is a Resource
+func (HALCodec) Supports(v interface{}) bool {
+	return supportsResource(v)
+}
+
+// Marshal renders the provided Resource as a HAL document
+func (HALCodec) Marshal(v interface{}, _ *Accept) ([]byte, error) {
+	return marshalResource(v, buildHAL)
+}
+
+// Unmarshal parses a HAL document into the provided *Resource, ignoring any
+// embedded resources
+func (HALCodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	return unmarshalResource(data, v, parseHAL)
+}
+
+// buildHAL builds the HAL document of a Resource
+func buildHAL(r *Resource) (interface{}, error) {
+	members := make(map[string]json.RawMessage)
+	attributes, err := r.attributes()
+	if err != nil {
+		return nil, err
+	}
+	if attributes != nil {
+		if err := json.Unmarshal(attributes, &members); err != nil {
+			return nil, err
+		}
+	}
+	doc := make(map[string]interface{}, len(members)+2)
+	for key, val := range members {
+		doc[key] = val
+	}
+
+	links := make(map[string][]halLink)
+	for _, link := range r.Links {
+		links[link.Rel] = append(links[link.Rel], halLink{link.Href, link.Title, link.Type})
+	}
+	if len(links) > 0 {
+		rendered := make(map[string]interface{}, len(links))
+		for rel, objs := range links {
+			if len(objs) == 1 {
+				rendered[rel] = objs[0]
+			} else {
+				rendered[rel] = objs
+			}
+		}
+		doc["_links"] = rendered
+	}
+
+	if len(r.Embedded) > 0 {
+		embedded := make(map[string][]interface{}, len(r.Embedded))
+		for _, rel := range r.embeddedRels() {
+			for _, res := range r.Embedded[rel] {
+				obj, err := buildHAL(res)
+				if err != nil {
+					return nil, err
+				}
+				embedded[rel] = append(embedded[rel], obj)
+			}
+		}
+		doc["_embedded"] = embedded
+	}
+	return doc, nil
+}
+
+// parseHAL parses a HAL document into a Resource
+func parseHAL(data []byte, r *Resource) error {
+	members := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	if raw, ok := members["_links"]; ok {
+		links := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(raw, &links); err != nil {
+			return err
+		}
+		rels := make([]string, 0, len(links))
+		for rel := range links {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+
+		for _, rel := range rels {
+			var objs []halLink
+			if err := json.Unmarshal(links[rel], &objs); err != nil {
+				var obj halLink
+				if err := json.Unmarshal(links[rel], &obj); err != nil {
+					return err
+				}
+				objs = []halLink{obj}
+			}
+			for _, obj := range objs {
+				r.Links = append(r.Links, Link{rel, obj.Href, obj.Title, obj.Type})
+			}
+		}
+	}
+
+	delete(members, "_links")
+	delete(members, "_embedded")
+	attributes, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	return r.setAttributes(attributes)
+}
+
+// jsonAPIIdentifier is a JSON:API resource identifier object
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonAPIRelationship is a JSON:API relationship object
+type jsonAPIRelationship struct {
+	Data []jsonAPIIdentifier `json:"data"`
+}
+
+// jsonAPIResource is a JSON:API resource object
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id,omitempty"`
+	Attributes    json.RawMessage                `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+// jsonAPIDocument is a JSON:API top level document
+type jsonAPIDocument struct {
+	Data     *jsonAPIResource   `json:"data"`
+	Included []*jsonAPIResource `json:"included,omitempty"`
+}
+
+// JSONAPICodec is a SelectiveCodec which represents a Resource as an
+// application/vnd.api+json document. Embedded resources are rendered as
+// relationships of the primary resource, and included in the document once
+// each. It is an AcceptParamCodec understanding only the ext and profile
+// parameters, so that a Registry whose NegotiationPolicy rejects unknown
+// params does not negotiate JSON:API for media ranges with any others. Use
+// CheckJSONAPIRequest to enforce the rest of JSON:API's media type parameter
+// rules, such as those of the Content-Type header
+type JSONAPICodec struct{}
+
+// AcceptParams returns the ext and profile accept-params
+func (JSONAPICodec) AcceptParams() []string {
+	return []string{"ext", "profile"}
+}
+
+// Supports returns true if the provided value is a Resource
+func (JSONAPICodec) Supports(v interface{}) bool {
+	return supportsResource(v)
+}
+
+// Marshal renders the provided Resource as a JSON:API document
+func (JSONAPICodec) Marshal(v interface{}, _ *Accept) ([]byte, error) {
+	return marshalResource(v, buildJSONAPI)
+}
+
+// Unmarshal parses the primary resource of a JSON:API document into the
+// provided *Resource
+func (JSONAPICodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	return unmarshalResource(data, v, parseJSONAPI)
+}
+
+// buildJSONAPI builds the JSON:API document of a Resource
+func buildJSONAPI(r *Resource) (interface{}, error) {
+	doc := &jsonAPIDocument{}
+	seen := map[jsonAPIIdentifier]bool{{r.Type, r.ID}: true}
+
+	var build func(*Resource) (*jsonAPIResource, error)
+	build = func(res *Resource) (*jsonAPIResource, error) {
+		attributes, err := res.attributes()
+		if err != nil {
+			return nil, err
+		}
+		obj := &jsonAPIResource{Type: res.Type, ID: res.ID, Attributes: attributes}
+
+		for _, link := range res.Links {
+			if obj.Links == nil {
+				obj.Links = make(map[string]string)
+			}
+			obj.Links[link.Rel] = link.Href
+		}
+
+		for _, rel := range res.embeddedRels() {
+			if obj.Relationships == nil {
+				obj.Relationships = make(map[string]jsonAPIRelationship)
+			}
+			relationship := jsonAPIRelationship{Data: []jsonAPIIdentifier{}}
+			for _, related := range res.Embedded[rel] {
+				id := jsonAPIIdentifier{related.Type, related.ID}
+				relationship.Data = append(relationship.Data, id)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				included, err := build(related)
+				if err != nil {
+					return nil, err
+				}
+				doc.Included = append(doc.Included, included)
+			}
+			obj.Relationships[rel] = relationship
+		}
+		return obj, nil
+	}
+
+	data, err := build(r)
+	if err != nil {
+		return nil, err
+	}
+	doc.Data = data
+	return doc, nil
+}
+
+// parseJSONAPI parses the primary resource of a JSON:API document into a
+// Resource
+func parseJSONAPI(data []byte, r *Resource) error {
+	var doc jsonAPIDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Data == nil {
+		return nil
+	}
+
+	r.Type, r.ID = doc.Data.Type, doc.Data.ID
+	rels := make([]string, 0, len(doc.Data.Links))
+	for rel := range doc.Data.Links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		r.Links = append(r.Links, Link{Rel: rel, Href: doc.Data.Links[rel]})
+	}
+	return r.setAttributes(doc.Data.Attributes)
+}
+
+// isJSONAPI returns true if the provided media type is the JSON:API media
+// type, rather than a media range including it
+func isJSONAPI(mediaType MediaType) bool {
+	return strings.EqualFold(string(mediaType.Base()), JSONAPIMediaType)
+}
+
+// invalidJSONAPIParams returns true if the provided media type is the JSON:API
+// media type, and has parameters other than ext and profile
+func invalidJSONAPIParams(mediaType MediaType, params map[string]string) bool {
+	if !isJSONAPI(mediaType) {
+		return false
+	}
+	for key := range params {
+		if !jsonAPIParams[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckJSONAPIRequest enforces JSON:API's media type parameter rules. A
+// 415 Unsupported Media Type Problem is returned if the request's Content-Type
+// is the JSON:API media type with parameters other than ext and profile, and a
+// 406 Not Acceptable Problem is returned if the request's Accept header
+// includes the JSON:API media type, but every instance of it has such
+// parameters
+func CheckJSONAPIRequest(req *http.Request) error {
+	if header := req.Header.Get(ContentTypeHeader); len(header) > 0 {
+		contentType, err := ParseContentType(header)
+		if err != nil {
+			return err
+		}
+		if invalidJSONAPIParams(contentType.MediaType, contentType.Params) {
+			return NewProblem(http.StatusUnsupportedMediaType,
+				"JSON:API media type parameters other than ext and profile are not supported.")
+		}
+	}
+
+	header := req.Header.Get(AcceptHeaderKey)
+	if len(header) == 0 {
+		return nil
+	}
+	accepts, err := ParseHeader(header)
+	if err != nil {
+		return err
+	}
+
+	instances, invalid := 0, 0
+	for _, acpt := range accepts {
+		if !isJSONAPI(acpt.MediaRange) {
+			continue
+		}
+		instances++
+		if invalidJSONAPIParams(acpt.MediaRange, acpt.AcceptParams) {
+			invalid++
+		}
+	}
+	if instances > 0 && instances == invalid {
+		return NewProblem(http.StatusNotAcceptable,
+			"Every JSON:API media type in the Accept header has parameters other than ext and profile.")
+	}
+	return nil
+}
+
+// sirenLink is a Siren link
+type sirenLink struct {
+	Rel   []string  `json:"rel"`
+	Href  string    `json:"href"`
+	Title string    `json:"title,omitempty"`
+	Type  MediaType `json:"type,omitempty"`
+}
+
+// sirenEntity is a Siren entity, or sub-entity
+type sirenEntity struct {
+	Class      []string        `json:"class,omitempty"`
+	Rel        []string        `json:"rel,omitempty"`
+	Properties json.RawMessage `json:"properties,omitempty"`
+	Entities   []*sirenEntity  `json:"entities,omitempty"`
+	Links      []sirenLink     `json:"links,omitempty"`
+}
+
+// SirenCodec is a SelectiveCodec which represents a Resource as an
+// application/vnd.siren+json entity. The Resource's Type is rendered as its
+// class, its attributes as its properties and its embedded resources as
+// sub-entities. Siren has no notion of an ID, which must be included in the
+// Resource's attributes to be rendered
+type SirenCodec struct{}
+
+// Supports returns true if the provided value is a Resource
+func (SirenCodec) Supports(v interface{}) bool {
+	return supportsResource(v)
+}
+
+// Marshal renders the provided Resource as a Siren entity
+func (SirenCodec) Marshal(v interface{}, _ *Accept) ([]byte, error) {
+	return marshalResource(v, func(r *Resource) (interface{}, error) {
+		return buildSiren(r, "")
+	})
+}
+
+// Unmarshal parses a Siren entity into the provided *Resource, ignoring any
+// sub-entities
+func (SirenCodec) Unmarshal(data []byte, _ string, _ ContentTypeParams, v interface{}) error {
+	return unmarshalResource(data, v, parseSiren)
+}
+
+// buildSiren builds the Siren entity of a Resource, which is a sub-entity with
+// the provided relation if rel is not empty
+func buildSiren(r *Resource, rel string) (*sirenEntity, error) {
+	properties, err := r.attributes()
+	if err != nil {
+		return nil, err
+	}
+	entity := &sirenEntity{Properties: properties}
+	if len(r.Type) > 0 {
+		entity.Class = []string{r.Type}
+	}
+	if len(rel) > 0 {
+		entity.Rel = []string{rel}
+	}
+
+	for _, link := range r.Links {
+		entity.Links = append(entity.Links,
+			sirenLink{[]string{link.Rel}, link.Href, link.Title, link.Type})
+	}
+
+	for _, embeddedRel := range r.embeddedRels() {
+		for _, res := range r.Embedded[embeddedRel] {
+			sub, err := buildSiren(res, embeddedRel)
+			if err != nil {
+				return nil, err
+			}
+			entity.Entities = append(entity.Entities, sub)
+		}
+	}
+	return entity, nil
+}
+
+// parseSiren parses a Siren entity into a Resource
+func parseSiren(data []byte, r *Resource) error {
+	var entity sirenEntity
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return err
+	}
+
+	if len(entity.Class) > 0 {
+		r.Type = entity.Class[0]
+	}
+	for _, link := range entity.Links {
+		for _, rel := range link.Rel {
+			r.Links = append(r.Links, Link{rel, link.Href, link.Title, link.Type})
+		}
+	}
+	return r.setAttributes(entity.Properties)
+}
+
+// NewHypermediaCodecs returns Codecs which represent a Resource as HAL,
+// JSON:API or Siren. HAL is registered first, so that clients accepting any
+// media type receive HAL
+func NewHypermediaCodecs() *Codecs {
+	codecs := NewCodecs()
+	codecs.Register(HALMediaType, HALCodec{})
+	codecs.Register(JSONAPIMediaType, JSONAPICodec{})
+	codecs.Register(SirenMediaType, SirenCodec{})
+	return codecs
+}
@@ -0,0 +1,159 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	Total  int    `json:"total"`
+	Status string `json:"status"`
+}
+
+func newTestOrder() *Resource {
+	order := NewResource("orders", "1", testOrder{30, "shipped"}).
+		AddLink("self", "/orders/1")
+	customer := NewResource("customers", "7", map[string]string{"name": "Ann"}).
+		AddLink("self", "/customers/7")
+	return order.Embed("customer", customer)
+}
+
+func TestHypermediaMarshal(t *testing.T) {
+	testio := []struct {
+		accept   string
+		cType    string
+		expected string
+	}{
+		{"*/*", HALMediaType,
+			`{"_embedded":{"customer":[{"_links":{"self":{"href":"/customers/7"}},"name":"Ann"}]},` +
+				`"_links":{"self":{"href":"/orders/1"}},"status":"shipped","total":30}`},
+		{"application/vnd.api+json", JSONAPIMediaType,
+			`{"data":{"type":"orders","id":"1","attributes":{"total":30,"status":"shipped"},` +
+				`"relationships":{"customer":{"data":[{"type":"customers","id":"7"}]}},` +
+				`"links":{"self":"/orders/1"}},` +
+				`"included":[{"type":"customers","id":"7","attributes":{"name":"Ann"},` +
+				`"links":{"self":"/customers/7"}}]}`},
+		{"application/vnd.siren+json", SirenMediaType,
+			`{"class":["orders"],"properties":{"total":30,"status":"shipped"},` +
+				`"entities":[{"class":["customers"],"rel":["customer"],"properties":{"name":"Ann"},` +
+				`"links":[{"rel":["self"],"href":"/customers/7"}]}],` +
+				`"links":[{"rel":["self"],"href":"/orders/1"}]}`},
+	}
+
+	codecs := NewHypermediaCodecs()
+	registry := NewRegistry()
+	registry.RegisterCodecs(Resource{}, codecs)
+
+	for _, test := range testio {
+		_, acpt, err := registry.Negotiate(test.accept)
+		assert.Nil(t, err)
+
+		media := NewMedia(newTestOrder(), codecs)
+		cType, err := media.ContentType(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.cType, cType)
+
+		data, err := media.MarshalMedia(acpt)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+
+	acpt, _ := ParseAccept(HALMediaType)
+	_, err := HALCodec{}.Marshal(NewResource("x", "", []int{1}), acpt)
+	assert.Equal(t, ErrUnsupportedType, err)
+	_, err = HALCodec{}.Marshal(testOrder{}, acpt)
+	assert.Equal(t, ErrUnsupportedType, err)
+}
+
+func TestHypermediaUnmarshal(t *testing.T) {
+	testio := []struct {
+		codec Codec
+		data  string
+	}{
+		{HALCodec{}, `{"_links":{"self":{"href":"/orders/1"},"item":[{"href":"/items/1"}]},` +
+			`"_embedded":{},"total":30,"status":"shipped"}`},
+		{JSONAPICodec{}, `{"data":{"type":"orders","id":"1",` +
+			`"attributes":{"total":30,"status":"shipped"},"links":{"item":"/items/1","self":"/orders/1"}}}`},
+		{SirenCodec{}, `{"class":["orders"],"properties":{"total":30,"status":"shipped"},` +
+			`"links":[{"rel":["item"],"href":"/items/1"},{"rel":["self"],"href":"/orders/1"}]}`},
+	}
+
+	links := []Link{{Rel: "item", Href: "/items/1"}, {Rel: "self", Href: "/orders/1"}}
+	for _, test := range testio {
+		res := &Resource{Attributes: &testOrder{}}
+		assert.Nil(t, test.codec.Unmarshal([]byte(test.data), "", nil, res))
+		assert.Equal(t, &testOrder{30, "shipped"}, res.Attributes)
+		assert.Equal(t, links, res.Links)
+
+		res = &Resource{}
+		assert.Nil(t, test.codec.Unmarshal([]byte(test.data), "", nil, res))
+		assert.Equal(t, map[string]interface{}{"total": float64(30), "status": "shipped"}, res.Attributes)
+
+		assert.Equal(t, ErrUnsupportedType, test.codec.Unmarshal([]byte(test.data), "", nil, Resource{}))
+	}
+}
+
+func TestJSONAPINegotiation(t *testing.T) {
+	testio := []struct {
+		accept    string
+		mediaType MediaType
+		err       error
+	}{
+		{"application/vnd.api+json", JSONAPIMediaType, nil},
+		{`application/vnd.api+json;ext="https://example.com/ext"`, JSONAPIMediaType, nil},
+		{`application/vnd.api+json;profile="https://example.com/p"`, JSONAPIMediaType, nil},
+		{"application/vnd.api+json;charset=utf-8", "", ErrNoContentType},
+		{"application/vnd.api+json;charset=utf-8, application/hal+json;q=0.5", HALMediaType, nil},
+	}
+
+	registry := NewRegistryWithPolicy(NegotiationPolicy{StrictQuality: true, RejectUnknownParams: true})
+	registry.RegisterCodecs(newTestOrder(), NewHypermediaCodecs())
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			_, acpt, err := registry.Negotiate(test.accept)
+			assert.Equal(t, test.err, err)
+			if err != nil {
+				assert.Equal(t, http.StatusNotAcceptable, NegotiationProblem(err).Status)
+				return
+			}
+			assert.Equal(t, test.mediaType, acpt.MediaRange.Base())
+		})
+	}
+}
+
+func TestCheckJSONAPIRequest(t *testing.T) {
+	testio := []struct {
+		contentType string
+		accept      string
+		status      int
+	}{
+		{"", "", 0},
+		{"application/vnd.api+json", "application/vnd.api+json", 0},
+		{`application/vnd.api+json;ext="https://example.com/ext"`, `application/vnd.api+json;profile="https://example.com/p"`, 0},
+		{"application/json;charset=utf-8", "application/json;indent=2", 0},
+		{"application/vnd.api+json;charset=utf-8", "", http.StatusUnsupportedMediaType},
+		{"", "application/vnd.api+json;charset=utf-8", http.StatusNotAcceptable},
+		{"", "application/vnd.api+json;charset=utf-8, application/vnd.api+json", 0},
+		{"", "application/vnd.api+json;charset=utf-8, */*", http.StatusNotAcceptable},
+	}
+
+	for _, test := range testio {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		if len(test.contentType) > 0 {
+			req.Header.Set(ContentTypeHeader, test.contentType)
+		}
+		if len(test.accept) > 0 {
+			req.Header.Set(AcceptHeaderKey, test.accept)
+		}
+
+		err := CheckJSONAPIRequest(req)
+		if test.status == 0 {
+			assert.Nil(t, err)
+			continue
+		}
+		assert.Equal(t, test.status, err.(*Problem).Status)
+	}
+}
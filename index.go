@@ -0,0 +1,102 @@
+package negotiator
+
+import (
+	"sort"
+	"strings"
+)
+
+// registryIndex indexes the registrations of a Registry by type, by type and
+// subtype, and by type and structured syntax suffix, allowing the
+// registrations included in a media range to be found without scanning every
+// registration. Each index holds positions in the Registry's registrations in
+// the order in which they were registered
+type registryIndex struct {
+	byType    map[string][]int
+	bySubType map[string][]int
+	bySuffix  map[string][]int
+}
+
+// newRegistryIndex returns an empty registryIndex
+func newRegistryIndex() *registryIndex {
+	return &registryIndex{byType: make(map[string][]int),
+		bySubType: make(map[string][]int),
+		bySuffix:  make(map[string][]int)}
+}
+
+// subTypeKey returns the index key of a media type's type and subtype
+func subTypeKey(typ, subType string) string {
+	return strings.ToLower(typ) + "/" + strings.ToLower(subType)
+}
+
+// suffixKey returns the index key of a media type's type and structured
+// syntax suffix
+func suffixKey(typ, suffix string) string {
+	return strings.ToLower(typ) + "/+" + strings.ToLower(suffix)
+}
+
+// add indexes the registration at the provided position
+func (idx *registryIndex) add(pos int, reg *registration) {
+	typ := reg.mediaType.Type()
+	key := strings.ToLower(typ)
+	idx.byType[key] = append(idx.byType[key], pos)
+
+	key = subTypeKey(typ, reg.mediaType.SubType())
+	idx.bySubType[key] = append(idx.bySubType[key], pos)
+
+	if suffix := reg.mediaType.Suffix(); len(suffix) > 0 {
+		key = suffixKey(typ, suffix)
+		idx.bySuffix[key] = append(idx.bySuffix[key], pos)
+	}
+}
+
+// lookup returns the positions of the registrations which may be included in
+// the provided media range, and false if every registration may be
+func (idx *registryIndex) lookup(media MediaType) ([]int, bool) {
+	typ, sub := media.Type(), media.SubType()
+	switch {
+	case typ == WildCard:
+		return nil, false
+	case sub == WildCard:
+		return idx.byType[strings.ToLower(typ)], true
+	case strings.HasPrefix(sub, WildCard+"+"):
+		return idx.bySuffix[suffixKey(typ, media.Suffix())], true
+	}
+	return idx.bySubType[subTypeKey(typ, sub)], true
+}
+
+// add appends a registration to the Registry, indexing it
+func (r *Registry) add(reg *registration) {
+	if r.index == nil {
+		r.index = newRegistryIndex()
+	}
+	r.registrations = append(r.registrations, reg)
+	r.index.add(len(r.registrations)-1, reg)
+}
+
+// each calls the provided function with every registration which may be
+// included in the provided media range, in the order in which they were
+// registered
+func (r *Registry) each(media MediaType, fn func(int, *registration)) {
+	if r.index != nil {
+		if positions, ok := r.index.lookup(media); ok {
+			for _, pos := range positions {
+				fn(pos, r.registrations[pos])
+			}
+			return
+		}
+	}
+	for pos, reg := range r.registrations {
+		fn(pos, reg)
+	}
+}
+
+// sortedPositions returns the keys of the provided map of registration
+// positions in registration order
+func sortedPositions(matches map[int]*candidate) []int {
+	positions := make([]int, 0, len(matches))
+	for pos := range matches {
+		positions = append(positions, pos)
+	}
+	sort.Ints(positions)
+	return positions
+}
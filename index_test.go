@@ -0,0 +1,113 @@
+package negotiator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newIndexedRegistry(policy NegotiationPolicy) *Registry {
+	reg := NewRegistryWithPolicy(policy)
+	reg.Register("application/json", testGeneric{})
+	reg.Register("application/vnd.dyn.zone+json;version=2", testSpecific{})
+	reg.Register("application/vnd.dyn.zone+json", testSpecific{})
+	reg.Register("application/XML", testGeneric{})
+	reg.Register("application/vnd.dyn.zone+xml", testSpecific{})
+	reg.Register("text/html", testGeneric{})
+	reg.Register("text/*", testGeneric{})
+	return reg
+}
+
+func TestRegistryIndexLookup(t *testing.T) {
+	reg := newIndexedRegistry(LegacyPolicy)
+	testIO := []struct {
+		media     MediaType
+		positions []int
+		indexed   bool
+	}{
+		{"*/*", nil, false},
+		{"application/*", []int{0, 1, 2, 3, 4}, true},
+		{"Text/*", []int{5, 6}, true},
+		{"application/*+json", []int{1, 2}, true},
+		{"application/*+XML", []int{4}, true},
+		{"application/xml", []int{3}, true},
+		{"application/vnd.dyn.zone+json;version=2", []int{1, 2}, true},
+		{"text/*+json", nil, true},
+		{"image/png", nil, true},
+	}
+
+	for _, test := range testIO {
+		t.Run(string(test.media), func(t *testing.T) {
+			positions, indexed := reg.index.lookup(test.media)
+			assert.Equal(t, test.indexed, indexed)
+			assert.Equal(t, test.positions, positions)
+		})
+	}
+}
+
+func TestRegistryIndexReplacement(t *testing.T) {
+	reg := newIndexedRegistry(LegacyPolicy)
+	reg.Register("application/xml", testSpecific{})
+
+	positions, _ := reg.index.lookup("application/xml")
+	assert.Equal(t, []int{3}, positions)
+	val, _, err := reg.Negotiate("application/xml")
+	assert.Nil(t, err)
+	assert.Equal(t, testSpecific{}, val)
+
+	selected := reg.Select(func(m MediaType) bool { return m.Suffix() == "json" })
+	positions, _ = selected.index.lookup("application/*+json")
+	assert.Equal(t, []int{0, 1}, positions)
+}
+
+func TestRegistryIndexMatchesScan(t *testing.T) {
+	headers := []string{
+		"*/*",
+		"application/*",
+		"application/*+json, application/json;q=0.9",
+		"application/vnd.dyn.zone+json;version=2",
+		"application/vnd.dyn.zone+json;q=0.5, application/*+xml;q=0.5",
+		"text/plain, */*;q=0.1",
+		"text/html;q=0, text/*;q=0.5, application/json;q=0.4",
+		"image/png",
+	}
+
+	for _, policy := range []NegotiationPolicy{LegacyPolicy, StrictPolicy} {
+		indexed := newIndexedRegistry(policy)
+		scanned := &Registry{registrations: indexed.registrations, policy: policy}
+		for _, header := range headers {
+			t.Run(header, func(t *testing.T) {
+				val, acpt, err := indexed.Negotiate(header)
+				expectedVal, expectedAcpt, expectedErr := scanned.Negotiate(header)
+				assert.Equal(t, expectedErr, err)
+				assert.Equal(t, expectedVal, val)
+				assert.Equal(t, expectedAcpt, acpt)
+			})
+		}
+	}
+}
+
+func BenchmarkRegistryNegotiateVendorTypes(b *testing.B) {
+	header := "application/vnd.vendor42+json;q=0.9, application/*+xml;q=0.5"
+	for _, size := range []int{10, 100, 500} {
+		for _, policy := range []NegotiationPolicy{LegacyPolicy, StrictPolicy} {
+			reg := NewRegistryWithPolicy(policy)
+			for i := 0; i < size; i++ {
+				reg.Register(fmt.Sprintf("application/vnd.vendor%d+json", i), testSpecific{})
+			}
+			reg.Register("application/vnd.vendor+xml", testGeneric{})
+
+			name := fmt.Sprintf("legacy/%d", size)
+			if policy.StrictQuality {
+				name = fmt.Sprintf("strict/%d", size)
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					reg.Negotiate(header)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package negotiator
+
+import (
+	"sort"
+	"strings"
+)
+
+// The RFC-6838 registration trees a media type's subtype may belong to
+const (
+	// StandardsTree is the tree of media types registered with IANA, which
+	// have no facet prefix (e.g application/json)
+	StandardsTree = ""
+
+	// VendorTree is the tree of media types associated with publicly available
+	// products (e.g application/vnd.dyn.zone+json)
+	VendorTree = "vnd"
+
+	// PersonalTree is the tree of media types for experimental or non-public
+	// products (e.g image/prs.btif)
+	PersonalTree = "prs"
+
+	// UnregisteredTree is the tree of media types intended exclusively for use
+	// in private, local environments (e.g application/x.foo or the legacy
+	// application/x-foo)
+	UnregisteredTree = "x"
+)
+
+// MediaParams are a mapping of parameter to value argument strings, parsed
+// from arguments of the form "foo=bar"
+type MediaParams map[string]string
+
+// MediaType is the string representation of a media type, or media range, as
+// defined by RFC-6838 and RFC-7231 (e.g application/vnd.api+json;version=2)
+type MediaType string
+
+// ParseMediaType parses and normalizes the provided media type, returning
+// ErrInvalidMediaRange if the type or subtype are missing or malformed, and
+// ErrInvalidMediaParam if any of its parameters are malformed. Empty parameters,
+// such as those left by a trailing semicolon, are ignored. The type, subtype,
+// and parameter names of the returned MediaType are lower case
+func ParseMediaType(s string) (MediaType, error) {
+	var raw = strings.TrimSpace(s)
+	var params MediaParams
+	if idx := strings.Index(raw, ";"); idx != -1 {
+		params = make(MediaParams)
+		for _, param := range splitQuoted(raw[idx+1:], ';') {
+			if len(strings.TrimSpace(param)) == 0 {
+				continue
+			}
+			key, val, ok := parseParam(param)
+			if !ok {
+				return "", ErrInvalidMediaParam
+			}
+			params[key] = val
+		}
+		raw = strings.TrimSpace(raw[:idx])
+	}
+
+	idx := strings.Index(raw, "/")
+	if idx == -1 {
+		return "", ErrInvalidMediaRange
+	}
+	typ, subType := raw[:idx], raw[idx+1:]
+	if !isToken(typ) || !isToken(subType) {
+		return "", ErrInvalidMediaRange
+	}
+	if typ == WildCard && subType != WildCard {
+		return "", ErrInvalidMediaRange
+	}
+	return NewMediaType(typ, subType, params), nil
+}
+
+// quoteEscaper escapes the characters which must be escaped within a quoted
+// parameter value
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// NewMediaType builds a MediaType from its type, subtype and parameters. The
+// parameters are sorted by name, and their values are quoted if necessary
+func NewMediaType(typ, subType string, params MediaParams) MediaType {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(typ))
+	b.WriteString("/")
+	b.WriteString(strings.ToLower(subType))
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.WriteString(";")
+		b.WriteString(strings.ToLower(key))
+		b.WriteString("=")
+		if val := params[key]; isToken(val) {
+			b.WriteString(val)
+		} else {
+			b.WriteString(`"` + quoteEscaper.Replace(val) + `"`)
+		}
+	}
+	return MediaType(b.String())
+}
+
+// isToken returns true if the provided string is a non-empty RFC-7230 token
+func isToken(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the MediaType as a string
+func (m MediaType) String() string {
+	return string(m)
+}
+
+// Type returns the type of the media type instance. eg, a media type
+// "application/json" has a Type of "application"
+func (m MediaType) Type() string {
+	var s = string(m)
+	var idx = strings.Index(s, "/")
+	if idx == -1 {
+		return ""
+	}
+	return strings.TrimSpace(s[:idx])
+}
+
+// SubType returns the sub-type of the media type instance. eg, a media type
+// "application/json" has a SubType of "json"
+func (m MediaType) SubType() string {
+	var s = string(m)
+	var start = strings.Index(s, "/")
+	var end = strings.Index(s, ";")
+
+	if start == -1 {
+		return ""
+	}
+
+	if end < start {
+		return strings.TrimSpace(s[start+1:])
+	}
+	return strings.TrimSpace(s[start+1 : end])
+}
+
+// Tree returns the RFC-6838 registration tree facet of the media type's
+// subtype. eg, a media type "application/vnd.dyn.zone+json" has a Tree of
+// VendorTree. Both the "x." and legacy "x-" prefixes belong to the
+// UnregisteredTree, and all other media types belong to the StandardsTree
+func (m MediaType) Tree() string {
+	tree, _ := m.splitTree()
+	return tree
+}
+
+// Facet returns the facet name of the media type's subtype, which is the
+// subtype without its registration tree prefix or structured syntax suffix.
+// eg, a media type "application/vnd.dyn.zone+json" has a Facet of "dyn.zone"
+func (m MediaType) Facet() string {
+	_, facet := m.splitTree()
+	if idx := strings.LastIndex(facet, "+"); idx != -1 {
+		return facet[:idx]
+	}
+	return facet
+}
+
+// BelongsTo returns true if the media type is registered in the provided tree
+// and its facet name is, or is a dot separated descendant of, the provided
+// facet. eg, "application/vnd.dyn.zone+json" belongs to the VendorTree facets
+// "dyn" and "dyn.zone", but not to "dy". An empty facet matches every media
+// type in the tree
+func (m MediaType) BelongsTo(tree, facet string) bool {
+	if m.Tree() != tree {
+		return false
+	}
+
+	name := strings.ToLower(m.Facet())
+	facet = strings.ToLower(facet)
+	return len(facet) == 0 || name == facet || strings.HasPrefix(name, facet+".")
+}
+
+// splitTree splits the media type's subtype into its registration tree and
+// the remainder of the subtype
+func (m MediaType) splitTree() (string, string) {
+	var sub = m.SubType()
+	if len(sub) > 2 && strings.EqualFold(sub[:2], "x-") {
+		return UnregisteredTree, sub[2:]
+	}
+
+	var idx = strings.Index(sub, ".")
+	if idx == -1 {
+		return StandardsTree, sub
+	}
+	switch tree := strings.ToLower(sub[:idx]); tree {
+	case VendorTree, PersonalTree, UnregisteredTree:
+		return tree, sub[idx+1:]
+	}
+	return StandardsTree, sub
+}
+
+// Suffix returns a structured media type suffix, as defined by RFC-6839, if
+// one is present. Eg, a media type of application/resource+json returns
+// "json". Because a these suffixes are optional, if no suffix is present an
+// empty string is returned
+func (m MediaType) Suffix() string {
+	var sub = m.SubType()
+	var idx = strings.LastIndex(sub, "+")
+	if idx == -1 {
+		return ""
+	}
+	return sub[idx+1:]
+}
+
+// Params returns the parameters of the media type, or nil if the media type
+// has no parameters. Malformed parameters are ignored
+func (m MediaType) Params() MediaParams {
+	var s = string(m)
+	var idx = strings.Index(s, ";")
+	if idx == -1 {
+		return nil
+	}
+
+	params := make(MediaParams)
+	for _, param := range splitQuoted(s[idx+1:], ';') {
+		if key, val, ok := parseParam(param); ok {
+			params[key] = val
+		}
+	}
+	return params
+}
+
+// Base returns the media type without any of its parameters
+func (m MediaType) Base() MediaType {
+	var s = string(m)
+	if idx := strings.Index(s, ";"); idx != -1 {
+		return MediaType(strings.TrimSpace(s[:idx]))
+	}
+	return m
+}
+
+// IsWildcard returns true if either the type or subtype of the media type is a
+// wildcard
+func (m MediaType) IsWildcard() bool {
+	return m.Type() == WildCard || strings.HasPrefix(m.SubType(), WildCard)
+}
+
+// Matches returns true if the two media types are compatible with one
+// another, treating wildcards in either media type as matching any value.
+// Parameters present in both media types must have equal values
+func (m MediaType) Matches(other MediaType) bool {
+	if !m.containsType(other) && !other.containsType(m) {
+		return false
+	}
+
+	otherParams := other.Params()
+	for key, val := range m.Params() {
+		if otherVal, ok := otherParams[key]; ok && otherVal != val {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains returns true if the media type, as a media range, includes the
+// other media type. eg, "text/*" contains "text/html", and "*/*" contains
+// every media type. A wildcarded subtype with a suffix, such as
+// "application/*+json", contains all subtypes with that suffix. Every
+// parameter of the media range must be present in the other media type with
+// an equal value
+func (m MediaType) Contains(other MediaType) bool {
+	if !m.containsType(other) {
+		return false
+	}
+
+	otherParams := other.Params()
+	for key, val := range m.Params() {
+		if otherParams[key] != val {
+			return false
+		}
+	}
+	return true
+}
+
+// containsType returns true if the type and subtype of the media type include
+// those of the other media type, ignoring any parameters
+func (m MediaType) containsType(other MediaType) bool {
+	typ, otherTyp := m.Type(), other.Type()
+	if typ == WildCard {
+		return true
+	}
+	if !strings.EqualFold(typ, otherTyp) {
+		return false
+	}
+
+	sub, otherSub := m.SubType(), other.SubType()
+	switch {
+	case sub == WildCard:
+		return true
+	case strings.HasPrefix(sub, WildCard+"+"):
+		return strings.EqualFold(m.Suffix(), other.Suffix())
+	}
+	return strings.EqualFold(sub, otherSub)
+}
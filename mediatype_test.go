@@ -0,0 +1,146 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMediaType(t *testing.T) {
+	testio := []struct {
+		inp      string
+		expected MediaType
+		err      error
+	}{
+		{"application/json", "application/json", nil},
+		{" Application/JSON ", "application/json", nil},
+		{"text/*", "text/*", nil},
+		{"*/*", "*/*", nil},
+		{"application/json; Charset=utf-8", "application/json;charset=utf-8", nil},
+		{`application/json;profile="urn:x:v2";a=b`,
+			`application/json;a=b;profile="urn:x:v2"`, nil},
+		{"application resource", "", ErrInvalidMediaRange},
+		{"application/", "", ErrInvalidMediaRange},
+		{"/json", "", ErrInvalidMediaRange},
+		{"*/json", "", ErrInvalidMediaRange},
+		{"application/json/xml", "", ErrInvalidMediaRange},
+		{"application/json;foobar", "", ErrInvalidMediaParam},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			mediaType, err := ParseMediaType(test.inp)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, mediaType)
+		})
+	}
+}
+
+func TestMediaTypeAccessors(t *testing.T) {
+	testio := []struct {
+		inp    MediaType
+		typ    string
+		subtyp string
+		tree   string
+		suffix string
+		params MediaParams
+	}{
+		{"application/json", "application", "json", "", "", nil},
+		{"application/resource+json", "application", "resource+json", "", "json", nil},
+		{"application/vnd.dyn.zone+json;version=2", "application", "vnd.dyn.zone+json",
+			"vnd", "json", MediaParams{"version": "2"}},
+		{"image/prs.btif", "image", "prs.btif", "prs", "", nil},
+		{"application/x.foo+xml", "application", "x.foo+xml", "x", "xml", nil},
+		{"application/x-www-form-urlencoded", "application", "x-www-form-urlencoded", "x", "", nil},
+		{"application/vndfoo.bar", "application", "vndfoo.bar", "", "", nil},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp.String(), func(t *testing.T) {
+			assert.Equal(t, test.typ, test.inp.Type())
+			assert.Equal(t, test.subtyp, test.inp.SubType())
+			assert.Equal(t, test.tree, test.inp.Tree())
+			assert.Equal(t, test.suffix, test.inp.Suffix())
+			assert.Equal(t, test.params, test.inp.Params())
+		})
+	}
+}
+
+func TestMediaTypeContains(t *testing.T) {
+	testio := []struct {
+		rng      MediaType
+		other    MediaType
+		contains bool
+		matches  bool
+	}{
+		{"application/json", "application/json", true, true},
+		{"application/json", "APPLICATION/JSON", true, true},
+		{"application/*", "application/json", true, true},
+		{"application/json", "application/*", false, true},
+		{"*/*", "text/html", true, true},
+		{"text/*", "application/json", false, false},
+		{"application/*+json", "application/vnd.api+json", true, true},
+		{"application/*+json", "application/vnd.api+xml", false, false},
+		{"text/html;level=1", "text/html;level=1", true, true},
+		{"text/html;level=1", "text/html", false, true},
+		{"text/html", "text/html;level=1", true, true},
+		{"text/html;level=1", "text/html;level=2", false, false},
+		{"text/html;level=1;x=1", "text/html;level=1;y=2", false, true},
+		{"text/*;level=1", "text/html;level=2", false, false},
+	}
+
+	for _, test := range testio {
+		t.Run(test.rng.String()+" "+test.other.String(), func(t *testing.T) {
+			assert.Equal(t, test.contains, test.rng.Contains(test.other))
+			assert.Equal(t, test.matches, test.rng.Matches(test.other))
+		})
+	}
+}
+
+func TestMediaTypeFacets(t *testing.T) {
+	testio := []struct {
+		inp   MediaType
+		tree  string
+		facet string
+	}{
+		{"application/json", StandardsTree, "json"},
+		{"application/resource+json", StandardsTree, "resource"},
+		{"application/vnd.dyn.zone+json", VendorTree, "dyn.zone"},
+		{"application/VND.Dyn.Zone", VendorTree, "Dyn.Zone"},
+		{"image/prs.btif", PersonalTree, "btif"},
+		{"application/x.foo+xml", UnregisteredTree, "foo"},
+		{"application/x-www-form-urlencoded", UnregisteredTree, "www-form-urlencoded"},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp.String(), func(t *testing.T) {
+			assert.Equal(t, test.tree, test.inp.Tree())
+			assert.Equal(t, test.facet, test.inp.Facet())
+		})
+	}
+}
+
+func TestMediaTypeBelongsTo(t *testing.T) {
+	testio := []struct {
+		inp      MediaType
+		tree     string
+		facet    string
+		expected bool
+	}{
+		{"application/vnd.dyn.zone+json", VendorTree, "dyn", true},
+		{"application/vnd.dyn.zone+json", VendorTree, "dyn.zone", true},
+		{"application/vnd.dyn.zone+json", VendorTree, "DYN", true},
+		{"application/vnd.dyn.zone+json", VendorTree, "", true},
+		{"application/vnd.dyn.zone+json", VendorTree, "dy", false},
+		{"application/vnd.dynamo+json", VendorTree, "dyn", false},
+		{"application/vnd.dyn.zone+json", PersonalTree, "dyn", false},
+		{"application/json", StandardsTree, "", true},
+		{"application/json", VendorTree, "", false},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp.String()+" "+test.facet, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.inp.BelongsTo(test.tree, test.facet))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package negotiator
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+// OctetStreamMediaType is the media type of arbitrary binary data
+const OctetStreamMediaType = "application/octet-stream"
+
+var (
+	// ErrNotMultipart is the error returned when a multipart request body is
+	// expected, but the request's Content-Type is not a multipart media type
+	// with a boundary
+	ErrNotMultipart = errors.New("Not A Multipart Media Type")
+
+	// ErrNotContentNegotiator is the error returned when a value registered in
+	// a Registry must be unmarshalled, but does not implement the
+	// ContentNegotiator interface
+	ErrNotContentNegotiator = errors.New("Registered Value Is Not A ContentNegotiator")
+)
+
+// Binary is a ContentNegotiator which holds the raw, unparsed bytes of a
+// representation, such as a file uploaded as part of a multipart request
+type Binary struct {
+	MediaType MediaType
+	Params    ContentTypeParams
+	Data      []byte
+}
+
+// ContentType returns the media type of the binary data, or
+// application/octet-stream if its media type is unknown
+func (b *Binary) ContentType(*Accept) (string, error) {
+	if len(b.MediaType) == 0 {
+		return OctetStreamMediaType, nil
+	}
+	return string(b.MediaType), nil
+}
+
+// MarshalMedia returns the binary data as is
+func (b *Binary) MarshalMedia(*Accept) ([]byte, error) {
+	return b.Data, nil
+}
+
+// UnmarshalMedia stores the provided media type, parameters and body
+func (b *Binary) UnmarshalMedia(cType string, params ContentTypeParams, body []byte) error {
+	b.MediaType = MediaType(cType)
+	b.Params = params
+	b.Data = body
+	return nil
+}
+
+// Part is a single part of a multipart request body, unmarshalled into a copy
+// of the value registered for its Content-Type
+type Part struct {
+	// Name is the form field name of a multipart/form-data part
+	Name string
+
+	// FileName is the file name of a part containing an uploaded file
+	FileName string
+
+	// ContentType is the part's parsed Content-Type header
+	ContentType *ContentType
+
+	// Value is a pointer to a copy of the value registered for the part's
+	// media type, into which the part's body has been unmarshalled
+	Value ContentNegotiator
+}
+
+// newNegotiator returns a pointer to a copy of the provided registered value,
+// as a ContentNegotiator
+func newNegotiator(val interface{}) (ContentNegotiator, error) {
+	ptr := reflect.New(reflect.TypeOf(val))
+	ptr.Elem().Set(reflect.ValueOf(val))
+	cn, ok := ptr.Interface().(ContentNegotiator)
+	if !ok {
+		return nil, ErrNotContentNegotiator
+	}
+	return cn, nil
+}
+
+// UnmarshalMultipart reads a multipart/form-data or multipart/mixed request
+// body, unmarshalling each part into a copy of the value registered for the
+// part's own Content-Type, which must implement ContentNegotiator. This allows
+// an upload containing a JSON metadata part and binary file parts, registered
+// using the Binary type, to be unmarshalled in a single call.
+//
+// A part without a Content-Type is treated as text/plain, as defined by
+// RFC-7578, and is unmarshalled into a Binary if text/plain is not registered.
+// ErrNoContentType is returned if any other part's media type is not
+// registered
+func (r *Registry) UnmarshalMultipart(req *http.Request) ([]*Part, error) {
+	header := req.Header.Get(ContentTypeHeader)
+	if len(header) == 0 {
+		return nil, ErrNoContentType
+	}
+
+	contentType, err := ParseContentType(header)
+	if err != nil {
+		return nil, err
+	}
+	if contentType.MediaType.Type() != "multipart" || len(contentType.Boundary()) == 0 {
+		return nil, ErrNotMultipart
+	}
+
+	var parts []*Part
+	reader := multipart.NewReader(req.Body, contentType.Boundary())
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		part, err := r.unmarshalPart(p)
+		p.Close()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+}
+
+// unmarshalPart unmarshals a single multipart part into a copy of the value
+// registered for its Content-Type
+func (r *Registry) unmarshalPart(p *multipart.Part) (*Part, error) {
+	header := p.Header.Get(ContentTypeHeader)
+	untyped := len(header) == 0
+	if untyped {
+		header = "text/plain"
+	}
+
+	contentType, err := ParseContentType(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var cn ContentNegotiator
+	if c := r.match(contentType.MediaType, MediaParams(contentType.Params)); c != nil {
+		if cn, err = newNegotiator(c.reg.value); err != nil {
+			return nil, err
+		}
+	} else if untyped {
+		cn = &Binary{}
+	} else {
+		return nil, ErrNoContentType
+	}
+
+	body, err := ioutil.ReadAll(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := cn.UnmarshalMedia(string(contentType.MediaType), contentType.Params, body); err != nil {
+		return nil, err
+	}
+
+	return &Part{Name: p.FormName(),
+		FileName:    p.FileName(),
+		ContentType: contentType,
+		Value:       cn}, nil
+}
@@ -0,0 +1,134 @@
+package negotiator
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMultipartPart struct {
+	name        string
+	fileName    string
+	contentType string
+	body        string
+}
+
+func newMultipartRequest(t *testing.T, subtype string, parts []testMultipartPart) *http.Request {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		if len(part.name) > 0 {
+			disposition := `form-data; name="` + part.name + `"`
+			if len(part.fileName) > 0 {
+				disposition += `; filename="` + part.fileName + `"`
+			}
+			header.Set(ContentDispositionHeader, disposition)
+		}
+		if len(part.contentType) > 0 {
+			header.Set(ContentTypeHeader, part.contentType)
+		}
+		pw, err := w.CreatePart(header)
+		assert.Nil(t, err)
+		pw.Write([]byte(part.body))
+	}
+	assert.Nil(t, w.Close())
+
+	req, _ := http.NewRequest("POST", "http://example.com", &buf)
+	req.Header.Set(ContentTypeHeader, "multipart/"+subtype+"; boundary="+w.Boundary())
+	return req
+}
+
+func TestRegistryUnmarshalMultipart(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register(testContentNegotiatorType, &testCN{})
+	testReg.Register("image/png", Binary{})
+
+	req := newMultipartRequest(t, "form-data", []testMultipartPart{
+		{"metadata", "", testContentNegotiatorType, `{"foo": "baz", "bar": 12}`},
+		{"avatar", "avatar.png", "image/png", "\x89PNG\x0D\x0A\x1A\x0A"},
+		{"title", "", "", "hello"},
+	})
+
+	parts, err := testReg.UnmarshalMultipart(req)
+	assert.Nil(t, err)
+	assert.Len(t, parts, 3)
+
+	assert.Equal(t, "metadata", parts[0].Name)
+	assert.Equal(t, MediaType(testContentNegotiatorType), parts[0].ContentType.MediaType)
+	assert.Equal(t, newTcn("baz", 12), parts[0].Value)
+
+	assert.Equal(t, "avatar", parts[1].Name)
+	assert.Equal(t, "avatar.png", parts[1].FileName)
+	assert.Equal(t, &Binary{MediaType: "image/png", Params: ContentTypeParams{},
+		Data: []byte("\x89PNG\x0D\x0A\x1A\x0A")}, parts[1].Value)
+
+	assert.Equal(t, "title", parts[2].Name)
+	assert.Equal(t, MediaType("text/plain"), parts[2].ContentType.MediaType)
+	assert.Equal(t, []byte("hello"), parts[2].Value.(*Binary).Data)
+}
+
+func TestRegistryUnmarshalMultipartMixed(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register(testContentNegotiatorType, testCN{})
+	testReg.Register("text/plain", Binary{})
+
+	req := newMultipartRequest(t, "mixed", []testMultipartPart{
+		{"", "", testContentNegotiatorType, `{"foo": "a", "bar": 1}`},
+		{"", "", testContentNegotiatorType, `{"foo": "b", "bar": 2}`},
+		{"", "", "", "hello"},
+	})
+
+	parts, err := testReg.UnmarshalMultipart(req)
+	assert.Nil(t, err)
+	assert.Len(t, parts, 3)
+	assert.Equal(t, newTcn("a", 1), parts[0].Value)
+	assert.Equal(t, newTcn("b", 2), parts[1].Value)
+	assert.Equal(t, MediaType("text/plain"), parts[2].Value.(*Binary).MediaType)
+}
+
+func TestRegistryUnmarshalMultipartErrors(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register(testContentNegotiatorType, testCN{})
+	testReg.Register("application/json", testGeneric{})
+
+	testio := []struct {
+		name string
+		req  *http.Request
+		err  error
+	}{
+		{"unregistered part", newMultipartRequest(t, "form-data", []testMultipartPart{
+			{"avatar", "avatar.png", "image/png", "png"},
+		}), ErrNoContentType},
+		{"not a content negotiator", newMultipartRequest(t, "form-data", []testMultipartPart{
+			{"data", "", "application/json", "{}"},
+		}), ErrNotContentNegotiator},
+		{"invalid part type", newMultipartRequest(t, "form-data", []testMultipartPart{
+			{"data", "", "application", "{}"},
+		}), ErrInvalidMediaRange},
+	}
+
+	for _, test := range testio {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testReg.UnmarshalMultipart(test.req)
+			assert.Equal(t, test.err, err)
+		})
+	}
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("{}"))
+	_, err := testReg.UnmarshalMultipart(req)
+	assert.Equal(t, ErrNoContentType, err)
+
+	req.Header.Set(ContentTypeHeader, "application/json")
+	_, err = testReg.UnmarshalMultipart(req)
+	assert.Equal(t, ErrNotMultipart, err)
+
+	req.Header.Set(ContentTypeHeader, "multipart/mixed")
+	_, err = testReg.UnmarshalMultipart(req)
+	assert.Equal(t, ErrNotMultipart, err)
+}
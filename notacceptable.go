@@ -0,0 +1,79 @@
+package negotiator
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// AcceptHeaderKey is the constant value for the key indicating the Accept
+// header
+const AcceptHeaderKey = "Accept"
+
+// availableExtension is the Problem extension member listing the media types
+// available for a resource
+const availableExtension = "available"
+
+// notAcceptableDetail is the human readable explanation of a 406 Not
+// Acceptable response
+const notAcceptableDetail = "None of the media types in the Accept header are available for this resource."
+
+// notAcceptableHTML is the template used to render 406 Not Acceptable
+// responses for clients accepting HTML
+var notAcceptableHTML = template.Must(template.New("406").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Detail}}</p>
+<ul>
+{{range .Extensions.available}}<li>{{.}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// notAcceptableFormats is the Registry used to negotiate the format of 406 Not
+// Acceptable response bodies. Plain text is used when none of the other
+// formats are acceptable
+var notAcceptableFormats = newFormatRegistry(
+	responseFormat{"text/plain", renderNotAcceptableText},
+	responseFormat{"text/html", renderNotAcceptableHTML},
+	responseFormat{ProblemJSONMediaType, renderProblemJSON},
+	responseFormat{ProblemXMLMediaType, renderProblemXML},
+	responseFormat{"application/json", renderProblemJSON},
+	responseFormat{"application/xml", renderProblemXML},
+)
+
+// renderNotAcceptableText renders a 406 Not Acceptable response body as plain
+// text, listing one available media type per line
+func renderNotAcceptableText(w io.Writer, p *Problem) error {
+	if _, err := fmt.Fprintf(w, "%s\n\nAvailable representations:\n", p.Detail); err != nil {
+		return err
+	}
+	available, _ := p.Extensions[availableExtension].([]MediaType)
+	for _, mediaType := range available {
+		if _, err := fmt.Fprintf(w, "  %s\n", mediaType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderNotAcceptableHTML renders a 406 Not Acceptable response body as an
+// HTML page
+func renderNotAcceptableHTML(w io.Writer, p *Problem) error {
+	return notAcceptableHTML.Execute(w, p)
+}
+
+// NotAcceptable writes a 406 Not Acceptable response which enumerates the
+// media types registered in the provided Registry, as suggested by RFC-7231.
+// The response body is rendered as HTML, an RFC-7807 problem document, or
+// plain text, whichever is best supported by the request's Accept header.
+// Plain text is used if none of these are acceptable
+func NotAcceptable(w http.ResponseWriter, req *http.Request, registry *Registry) error {
+	p := NewProblem(http.StatusNotAcceptable, notAcceptableDetail)
+	p.Extensions[availableExtension] = registry.MediaTypes()
+	return negotiateResponseFormat(notAcceptableFormats, req).write(w, p)
+}
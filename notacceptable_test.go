@@ -0,0 +1,65 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotAcceptable(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("application/vnd.dyn.zone+json;version=2", testSpecific{})
+
+	testio := []struct {
+		accept      string
+		contentType string
+		contains    []string
+	}{
+		{"", "text/plain; charset=utf-8",
+			[]string{"  application/json\n", "  application/vnd.dyn.zone+json;version=2\n"}},
+		{"image/png", "text/plain; charset=utf-8",
+			[]string{"  application/json\n"}},
+		{"invalid", "text/plain; charset=utf-8",
+			[]string{"  application/json\n"}},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"text/html; charset=utf-8",
+			[]string{"<li>application/json</li>", "<li>application/vnd.dyn.zone&#43;json;version=2</li>"}},
+		{"application/*", "application/problem+json",
+			[]string{`"status":406`, `"available":["application/json","application/vnd.dyn.zone+json;version=2"]`}},
+		{"application/json", "application/json",
+			[]string{`"title":"Not Acceptable"`}},
+		{"application/problem+xml", "application/problem+xml",
+			[]string{`<problem xmlns="urn:ietf:rfc:7807">`,
+				"<available><i>application/json</i><i>application/vnd.dyn.zone+json;version=2</i></available>",
+				"<status>406</status>"}},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			w := httptest.NewRecorder()
+
+			err := NotAcceptable(w, req, testReg)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusNotAcceptable, w.Code)
+			assert.Equal(t, test.contentType, w.Header().Get(ContentTypeHeader))
+			for _, s := range test.contains {
+				assert.Contains(t, w.Body.String(), s)
+			}
+
+			if strings.Contains(test.contentType, "json") {
+				var body struct {
+					Available []MediaType `json:"available"`
+				}
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, testReg.MediaTypes(), body.Available)
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package negotiator
 
 import (
 	"sort"
-	"strings"
+	"sync"
 )
 
 // AcceptHeader is a slice of individual Accept instances representing an
@@ -13,14 +13,28 @@ type AcceptHeader []*Accept
 // arguments.
 type by func(a1, a2 *Accept) bool
 
+// insertionSortLimit is the length of the longest AcceptHeader sorted without
+// allocating, using an insertion sort
+const insertionSortLimit = 12
+
 // Sort is a method on the function type, By, that sorts the argument slice
-// according to the function.
+// according to the function. The sort is stable, and short accept headers are
+// sorted without allocating
 func (by by) Sort(accept AcceptHeader) {
+	if len(accept) <= insertionSortLimit {
+		for i := 1; i < len(accept); i++ {
+			for j := i; j > 0 && by(accept[j], accept[j-1]); j-- {
+				accept[j], accept[j-1] = accept[j-1], accept[j]
+			}
+		}
+		return
+	}
+
 	rs := &acceptSorter{
 		accepts: accept,
 		by:      by, // The Sort method's receiver is the function (closure) that defines the sort order.
 	}
-	sort.Sort(rs)
+	sort.Stable(rs)
 }
 
 // recordSorter joins a By function and a slice of Records to be sorted
@@ -51,22 +65,29 @@ func byWeight(a1, a2 *Accept) bool {
 }
 
 // ParseHeader parses an entire Accept header into an AcceptHeader instance and
-// sorts it according to the relative quality of the accept headers provided
+// sorts it according to the relative quality of the accept headers provided,
+// as defined by the LegacyPolicy. See NegotiationPolicy.ParseHeader for its
+// allocations
 func ParseHeader(header string) (AcceptHeader, error) {
-	var act *Accept
-	var accepts AcceptHeader
-	var err error
+	return LegacyPolicy.ParseHeader(header)
+}
 
-	values := strings.Split(header, ",")
-	for _, value := range values {
-		act = NewAccept()
-		err = act.Parse(strings.TrimSpace(value))
-		if err != nil {
-			return nil, err
-		}
-		accepts = append(accepts, act)
-	}
+// headerBuffer holds the slices an accept header is parsed into, allowing
+// them to be reused by a Registry between negotiations
+type headerBuffer struct {
+	header AcceptHeader
+	values []Accept
+}
 
-	by(byWeight).Sort(accepts)
-	return accepts, nil
+// headerBuffers pools the headerBuffers used by Registry negotiation
+var headerBuffers = sync.Pool{New: func() interface{} { return &headerBuffer{} }}
+
+// reset returns an AcceptHeader and backing slice of Accepts of length n,
+// growing the buffer if necessary
+func (b *headerBuffer) reset(n int) (AcceptHeader, []Accept) {
+	if cap(b.values) < n {
+		b.header = make(AcceptHeader, n)
+		b.values = make([]Accept, n)
+	}
+	return b.header[:n], b.values[:n]
 }
@@ -6,7 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func acceptWithMedia(media mediaRange, qual float64) *Accept {
+func acceptWithMedia(media MediaType, qual float64) *Accept {
 	a := NewAccept()
 	a.MediaRange = media
 	a.Quality = qual
@@ -52,3 +52,60 @@ func TestParseHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHeaderAllocations(t *testing.T) {
+	header := "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
+	buf := &headerBuffer{}
+	allocs := testing.AllocsPerRun(100, func() {
+		LegacyPolicy.parseHeader(header, buf)
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	accepts, err := LegacyPolicy.parseHeader(header, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(accepts))
+	for _, acpt := range accepts {
+		assert.Nil(t, acpt.AcceptParams)
+		assert.Nil(t, acpt.AcceptExt)
+	}
+
+	accepts, err = LegacyPolicy.parseHeader("application/json;indent=2;q=1;pretty", buf)
+	assert.Nil(t, err)
+	assert.Equal(t, MediaParams{"indent": "2"}, accepts[0].AcceptParams)
+	assert.Equal(t, MediaParams{"pretty": ""}, accepts[0].AcceptExt)
+}
+
+func BenchmarkParseHeader(b *testing.B) {
+	headers := map[string]string{
+		"single":  "application/json",
+		"browser": "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+		"params":  `application/vnd.api+json;ext="https://example.com/ext";q=1;version=2, application/json;indent=2`,
+	}
+
+	registry := NewRegistry()
+	registry.Register("application/json", testGeneric{})
+	registry.Register("text/html", testSpecific{})
+
+	for _, name := range []string{"single", "browser", "params"} {
+		header := headers[name]
+		b.Run("ParseHeader/"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ParseHeader(header)
+			}
+		})
+		b.Run("Negotiate/"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				registry.Negotiate(header)
+			}
+		})
+	}
+}
+
+func BenchmarkParseAccept(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseAccept("application/json")
+	}
+}
@@ -0,0 +1,163 @@
+package negotiator
+
+import "strings"
+
+// NegotiationPolicy controls the semantics used when parsing accept headers
+// and negotiating them against a Registry
+type NegotiationPolicy struct {
+	// StrictQuality enables the quality semantics defined by RFC-7231. A media
+	// range without a quality parameter has a quality of 1.0, the specificity
+	// of a media range is only used to break ties between media ranges of
+	// equal quality, and media ranges with a quality of 0 are not acceptable
+	StrictQuality bool
+
+	// RejectUnknownParams prevents a media range from matching a registration
+	// which declares the accept-params it understands, unless the
+	// registration understands or declares every accept-param of the media
+	// range. By default, unknown accept-params are ignored
+	RejectUnknownParams bool
+
+	// RequiredExtensions are the names of accept-ext which a registration must
+	// support to match a media range carrying them (e.g a required "version"
+	// extension prevents application/json;q=1;version=2 from matching a
+	// registration which does not support it). A registration supports the
+	// accept-ext declared by Registry.DeclareExtensions
+	RequiredExtensions []string
+}
+
+var (
+	// LegacyPolicy assigns default qualities to media ranges based on their
+	// specificity, as defined by the AcceptParamsQuality,
+	// MediaRangeSubTypeQuality, MediaRangeWildcardSubtypeQuality, and
+	// MediaRangeWildcardQuality constants. This is the default policy
+	LegacyPolicy = NegotiationPolicy{}
+
+	// StrictPolicy implements the quality semantics defined by RFC-7231
+	StrictPolicy = NegotiationPolicy{StrictQuality: true}
+)
+
+// ParseAccept parses the provided accept header value into a newly created
+// Accept struct according to the policy, and a conditional error. Its
+// AcceptParams and AcceptExt maps are always allocated
+func (p NegotiationPolicy) ParseAccept(header string) (*Accept, error) {
+	acpt := NewAccept()
+	err := acpt.parse(header, p)
+	if err != nil {
+		return nil, err
+	}
+	return acpt, nil
+}
+
+// ParseHeader parses an entire Accept header into an AcceptHeader instance and
+// sorts it in order of preference according to the policy. The AcceptParams
+// and AcceptExt maps of each Accept are always allocated, as the returned
+// AcceptHeader is owned by the caller. Registry.Negotiate instead reuses its
+// parsed headers, so only allocates the maps which are needed
+func (p NegotiationPolicy) ParseHeader(header string) (AcceptHeader, error) {
+	return p.parseHeader(header, nil)
+}
+
+// parseHeader implements ParseHeader. The Accepts are parsed into a single
+// backing slice. If a headerBuffer is provided it is reused, and the
+// AcceptParams and AcceptExt of each Accept are only allocated when a
+// parameter is parsed, so that parsing an accept header without parameters
+// only allocates when the buffer must grow
+func (p NegotiationPolicy) parseHeader(header string, buf *headerBuffer) (AcceptHeader, error) {
+	pooled := buf != nil
+	if !pooled {
+		buf = &headerBuffer{}
+	}
+	n := countQuoted(header, ',') + 1
+	accepts, values := buf.reset(n)
+
+	for i, rest, more := 0, header, true; more; i++ {
+		var value string
+		value, rest, more = cutQuoted(rest, ',')
+
+		act := &values[i]
+		*act = Accept{Quality: -1.0}
+		if !pooled {
+			act.AcceptParams, act.AcceptExt = make(MediaParams), make(MediaParams)
+		}
+		if err := act.parse(strings.TrimSpace(value), p); err != nil {
+			return nil, err
+		}
+		accepts[i] = act
+	}
+
+	if p.StrictQuality {
+		by(byPrecedence).Sort(accepts)
+	} else {
+		by(byWeight).Sort(accepts)
+	}
+	return accepts, nil
+}
+
+// acceptable returns true if the provided Accept may be matched against a
+// Registry under the policy
+func (p NegotiationPolicy) acceptable(a *Accept) bool {
+	return !p.StrictQuality || a.Quality > 0
+}
+
+// understands returns true if the provided registration understands every
+// provided accept-param, or if the policy ignores unknown accept-params. A
+// registration which has not declared its accept-params understands all of them
+func (p NegotiationPolicy) understands(reg *registration, params MediaParams) bool {
+	if !p.RejectUnknownParams || reg.acceptParams == nil {
+		return true
+	}
+	for key := range params {
+		if _, ok := reg.params[key]; !ok && !reg.acceptParams[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// supportsExtensions returns true if the provided registration supports every
+// accept-ext required by the policy which is present in the provided accept-ext
+func (p NegotiationPolicy) supportsExtensions(reg *registration, ext MediaParams) bool {
+	for _, name := range p.RequiredExtensions {
+		name = strings.ToLower(name)
+		if _, ok := ext[name]; ok && !reg.extensions[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultQuality returns the quality of the provided Accept if one was not
+// explicitly provided
+func (p NegotiationPolicy) defaultQuality(a *Accept) float64 {
+	if p.StrictQuality {
+		return 1.0
+	} else if len(a.AcceptParams) > 0 {
+		return AcceptParamsQuality
+	} else if a.MediaRange.SubType() != WildCard {
+		return MediaRangeSubTypeQuality
+	} else if a.MediaRange.Type() != WildCard {
+		return MediaRangeWildcardSubtypeQuality
+	}
+	return MediaRangeWildcardQuality
+}
+
+// specificity returns the relative specificity of an Accept's media range,
+// where */* is the least specific and a media range with accept-params is the
+// most specific
+func specificity(a *Accept) int {
+	if a.MediaRange.Type() == WildCard {
+		return 0
+	} else if a.MediaRange.SubType() == WildCard {
+		return 1
+	}
+	return 2 + len(a.AcceptParams)
+}
+
+// byPrecedence is a "by" closure which sorts based on an Accept's Quality
+// field, using the specificity of the media range to break ties
+func byPrecedence(a1, a2 *Accept) bool {
+	if a1.Quality != a2.Quality {
+		return a1.Quality > a2.Quality
+	}
+	return specificity(a1) > specificity(a2)
+}
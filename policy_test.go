@@ -0,0 +1,178 @@
+package negotiator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyAcceptQuality(t *testing.T) {
+	testio := []struct {
+		inp    string
+		legacy float64
+		strict float64
+	}{
+		{"application/json", 0.9, 1.0},
+		{"application/*", 0.8, 1.0},
+		{"*/*", 0.7, 1.0},
+		{"application/json;indent=4", 1.0, 1.0},
+		{"application/json;q=0.3", 0.3, 0.3},
+		{"*/*;q=0", 0, 0},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			acpt, err := LegacyPolicy.ParseAccept(test.inp)
+			assert.Nil(t, err, "Unable to parse valid header: %s", test.inp)
+			assert.Equal(t, test.legacy, acpt.Quality)
+
+			acpt, err = StrictPolicy.ParseAccept(test.inp)
+			assert.Nil(t, err, "Unable to parse valid header: %s", test.inp)
+			assert.Equal(t, test.strict, acpt.Quality)
+		})
+	}
+}
+
+func TestPolicyParseHeader(t *testing.T) {
+	testio := []struct {
+		inp    string
+		legacy []MediaType
+		strict []MediaType
+	}{
+		{"*/*, text/*, text/html",
+			[]MediaType{"text/html", "text/*", "*/*"},
+			[]MediaType{"text/html", "text/*", "*/*"}},
+		{"text/html, text/plain;format=flowed, text/*;q=0.8",
+			[]MediaType{"text/plain", "text/html", "text/*"},
+			[]MediaType{"text/plain", "text/html", "text/*"}},
+		{"text/*;q=0.9, */*, text/html;q=0.9",
+			[]MediaType{"text/*", "text/html", "*/*"},
+			[]MediaType{"*/*", "text/html", "text/*"}},
+	}
+
+	order := func(header AcceptHeader) []MediaType {
+		var ranges []MediaType
+		for _, acpt := range header {
+			ranges = append(ranges, acpt.MediaRange)
+		}
+		return ranges
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			header, err := LegacyPolicy.ParseHeader(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.legacy, order(header))
+
+			header, err = StrictPolicy.ParseHeader(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.strict, order(header))
+		})
+	}
+}
+
+func TestPolicyRegistryNegotiate(t *testing.T) {
+	testio := []struct {
+		inp       string
+		legacy    interface{}
+		strict    interface{}
+		strictErr error
+	}{
+		{"*/*, application/json", testGeneric{}, testGeneric{}, nil},
+		{"application/xml;q=0.95, application/json", testSpecific{}, testGeneric{}, nil},
+		{"application/json;q=0", testGeneric{}, nil, ErrNoContentType},
+	}
+
+	legacy := NewRegistry()
+	strict := NewRegistryWithPolicy(StrictPolicy)
+	for _, reg := range []*Registry{legacy, strict} {
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xml", testSpecific{})
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := legacy.Negotiate(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.legacy, i)
+
+			i, _, err = strict.Negotiate(test.inp)
+			assert.Equal(t, test.strictErr, err)
+			assert.Equal(t, test.strict, i)
+		})
+	}
+}
+
+func TestPolicyRejectUnknownParams(t *testing.T) {
+	testio := []struct {
+		inp      string
+		ignore   interface{}
+		reject   interface{}
+		rejected error
+	}{
+		{"application/json;indent=2", testGeneric{}, testGeneric{}, nil},
+		{"application/json;version=2", testGeneric{}, nil, ErrNoContentType},
+		{"application/json;version=2, application/xml", testGeneric{}, testSpecific{}, nil},
+		{"application/xml;version=2", testSpecific{}, testSpecific{}, nil},
+		{"application/vnd.dyn+json;profile=v1;indent=2", testProfileV1{}, testProfileV1{}, nil},
+	}
+
+	ignore := NewRegistry()
+	reject := NewRegistryWithPolicy(NegotiationPolicy{RejectUnknownParams: true})
+	for _, reg := range []*Registry{ignore, reject} {
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xml", testSpecific{})
+		reg.Register("application/vnd.dyn+json;profile=v1", testProfileV1{})
+		assert.Nil(t, reg.DeclareAcceptParams("application/json", "indent"))
+		assert.Nil(t, reg.DeclareAcceptParams("application/vnd.dyn+json;profile=v1", "indent"))
+		assert.Equal(t, ErrNoContentType, reg.DeclareAcceptParams("text/csv", "header"))
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := ignore.Negotiate(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.ignore, i)
+
+			i, _, err = reject.Negotiate(test.inp)
+			assert.Equal(t, test.rejected, err)
+			assert.Equal(t, test.reject, i)
+		})
+	}
+}
+
+func TestPolicyRequiredExtensions(t *testing.T) {
+	testio := []struct {
+		inp      string
+		optional interface{}
+		required interface{}
+		err      error
+	}{
+		{"application/json", testGeneric{}, testGeneric{}, nil},
+		{"application/json;q=1;version=2", testGeneric{}, nil, ErrNoContentType},
+		{"application/json;q=1;version=2, application/xml", testGeneric{}, testSpecific{}, nil},
+		{"application/xml;q=1;version=2", testSpecific{}, testSpecific{}, nil},
+		{"application/xml;q=1;VERSION=2;trace=on", testSpecific{}, testSpecific{}, nil},
+	}
+
+	optional := NewRegistry()
+	required := NewRegistryWithPolicy(NegotiationPolicy{RequiredExtensions: []string{"Version"}})
+	for _, reg := range []*Registry{optional, required} {
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xml", testSpecific{})
+		assert.Nil(t, reg.DeclareExtensions("application/xml", "version"))
+		assert.Equal(t, ErrNoContentType, reg.DeclareExtensions("text/csv", "version"))
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := optional.Negotiate(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.optional, i)
+
+			i, _, err = required.Negotiate(test.inp)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.required, i)
+		})
+	}
+}
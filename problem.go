@@ -0,0 +1,305 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+const (
+	// ProblemJSONMediaType is the media type of an RFC-7807 problem details
+	// object rendered as JSON
+	ProblemJSONMediaType = "application/problem+json"
+
+	// ProblemXMLMediaType is the media type of an RFC-7807 problem details
+	// object rendered as XML
+	ProblemXMLMediaType = "application/problem+xml"
+
+	// problemNamespace is the XML namespace of RFC-7807 problem documents
+	problemNamespace = "urn:ietf:rfc:7807"
+)
+
+// Problem is the struct representation of an RFC-7807 problem details object,
+// used to describe errors in HTTP responses. Any Extensions are rendered as
+// additional members of the problem details object
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem returns a Problem with the provided status and detail, and a
+// Type of "about:blank" titled with the status' text
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Type: "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     detail,
+		Extensions: make(map[string]interface{})}
+}
+
+// NegotiationProblem converts an error returned while negotiating an Accept
+// header into a Problem. ErrNoContentType results in a 406 Not Acceptable
+// problem, malformed Accept headers in a 400 Bad Request problem, and any
+// other error in a 500 Internal Server Error problem
+func NegotiationProblem(err error) *Problem {
+	if p, ok := err.(*Problem); ok {
+		return p
+	}
+
+	switch err {
+	case ErrNoContentType:
+		return NewProblem(http.StatusNotAcceptable, notAcceptableDetail)
+	case ErrInvalidMediaRange, ErrInvalidAcceptParam:
+		return NewProblem(http.StatusBadRequest,
+			fmt.Sprintf("The Accept header is malformed: %s.", err))
+	}
+	return NewProblem(http.StatusInternalServerError, err.Error())
+}
+
+// UnmarshalProblem converts an error returned while unmarshalling a request
+// body into a Problem. ErrNoContentType results in a 415 Unsupported Media
+// Type problem, a malformed Content-Type header or any other error in a 400
+// Bad Request problem
+func UnmarshalProblem(err error) *Problem {
+	if p, ok := err.(*Problem); ok {
+		return p
+	}
+
+	switch err {
+	case ErrNoContentType:
+		return NewProblem(http.StatusUnsupportedMediaType,
+			"The Content-Type of the request body is not supported.")
+	case ErrInvalidMediaRange, ErrInvalidMediaParam:
+		return NewProblem(http.StatusBadRequest,
+			fmt.Sprintf("The Content-Type header is malformed: %s.", err))
+	}
+	return NewProblem(http.StatusBadRequest, err.Error())
+}
+
+// Error implements the error interface, allowing a Problem to be returned as
+// an error
+func (p *Problem) Error() string {
+	if len(p.Detail) > 0 {
+		return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+	}
+	return p.Title
+}
+
+// members returns the members of the problem details object, including its
+// extensions, omitting any empty standard members
+func (p *Problem) members() map[string]interface{} {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, val := range p.Extensions {
+		members[key] = val
+	}
+	for key, val := range map[string]string{"type": p.Type, "title": p.Title,
+		"detail": p.Detail, "instance": p.Instance} {
+		if len(val) > 0 {
+			members[key] = val
+		}
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	return members
+}
+
+// MarshalJSON implements json.Marshaler, rendering the problem details object
+// as an application/problem+json document
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.members())
+}
+
+// MarshalXML implements xml.Marshaler, rendering the problem details object as
+// an application/problem+xml document. Slice and array extensions are
+// rendered as a sequence of <i> elements, as defined by RFC-7807
+func (p *Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Space: problemNamespace, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	members := p.members()
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := encodeProblemMember(e, key, members[key]); err != nil {
+			return err
+		}
+	}
+
+	if err := e.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// encodeProblemMember encodes a single problem details member as an XML
+// element. Slices and arrays are encoded as a sequence of <i> elements, and
+// maps as nested elements named by their sorted keys, as defined by RFC-7807
+func encodeProblemMember(e *xml.Encoder, key string, val interface{}) error {
+	el := xml.StartElement{Name: xml.Name{Local: key}}
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if err := e.EncodeToken(el); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeProblemMember(e, "i", v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(el.End())
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			name := fmt.Sprint(k.Interface())
+			keys = append(keys, name)
+			values[name] = v.MapIndex(k).Interface()
+		}
+		sort.Strings(keys)
+
+		if err := e.EncodeToken(el); err != nil {
+			return err
+		}
+		for _, name := range keys {
+			if err := encodeProblemMember(e, name, values[name]); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(el.End())
+	}
+	return e.EncodeElement(val, el)
+}
+
+// A format is a representation a response body generated by the package may
+// be rendered as
+type format interface {
+	// formatType returns the media type of the representation
+	formatType() MediaType
+}
+
+// newFormatRegistry returns a Registry used to negotiate which of the provided
+// formats a response body is rendered as. The first format is preferred when
+// several are equally acceptable
+func newFormatRegistry(formats ...format) *Registry {
+	registry := NewRegistryWithPolicy(StrictPolicy)
+	for _, f := range formats {
+		registry.Register(string(f.formatType()), f)
+	}
+	return registry
+}
+
+// negotiateFormat negotiates the format of a response body from the provided
+// Registry of formats, treating a request without an Accept header as
+// accepting any of them. The first registered format is returned, along with
+// an error, if the request's Accept header is malformed or none of the formats
+// are acceptable
+func negotiateFormat(formats *Registry, req *http.Request) (format, error) {
+	val, _, err := formats.Negotiate(acceptOrAny(req.Header.Get(AcceptHeaderKey)))
+	if err != nil {
+		return formats.registrations[0].value.(format), err
+	}
+	return val.(format), nil
+}
+
+// contentTypeFor returns the Content-Type header value used for a response
+// body of the provided media type
+func contentTypeFor(mediaType MediaType) string {
+	if mediaType.Type() == "text" {
+		return string(mediaType) + "; charset=utf-8"
+	}
+	return string(mediaType)
+}
+
+// responseFormat is a media type an error response body may be rendered as,
+// along with the function which renders it
+type responseFormat struct {
+	mediaType MediaType
+	render    func(io.Writer, *Problem) error
+}
+
+// formatType returns the media type of the response format
+func (f responseFormat) formatType() MediaType {
+	return f.mediaType
+}
+
+// negotiateResponseFormat negotiates the format of an error response body from
+// the provided Registry of response formats, falling back to the first
+// registered format when the request's Accept header is malformed or
+// unacceptable
+func negotiateResponseFormat(formats *Registry, req *http.Request) responseFormat {
+	f, _ := negotiateFormat(formats, req)
+	return f.(responseFormat)
+}
+
+// write writes the problem to w in the response format, using the problem's
+// status as the response status code, or 500 Internal Server Error if the
+// problem has no status. The problem is rendered before any headers are
+// written, so that nothing is written if it can not be rendered
+func (f responseFormat) write(w http.ResponseWriter, p *Problem) error {
+	var buf bytes.Buffer
+	if err := f.render(&buf, p); err != nil {
+		return err
+	}
+
+	AddVary(w.Header(), AcceptHeaderKey)
+	w.Header().Set(ContentTypeHeader, contentTypeFor(f.mediaType))
+
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// renderProblemJSON renders a problem details object as JSON
+func renderProblemJSON(w io.Writer, p *Problem) error {
+	return json.NewEncoder(w).Encode(p)
+}
+
+// renderProblemXML renders a problem details object as XML
+func renderProblemXML(w io.Writer, p *Problem) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(p)
+}
+
+// problemFormats is the Registry used to negotiate the format of problem
+// details responses
+var problemFormats = newFormatRegistry(
+	responseFormat{ProblemJSONMediaType, renderProblemJSON},
+	responseFormat{ProblemXMLMediaType, renderProblemXML},
+	responseFormat{"application/json", renderProblemJSON},
+	responseFormat{"application/xml", renderProblemXML},
+)
+
+// WriteProblem writes the provided Problem to w as either an
+// application/problem+json or application/problem+xml document, whichever is
+// best supported by the request's Accept header, using the Problem's Status as
+// the response status code. JSON is used if neither is acceptable
+func WriteProblem(w http.ResponseWriter, req *http.Request, p *Problem) error {
+	return negotiateResponseFormat(problemFormats, req).write(w, p)
+}
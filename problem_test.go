@@ -0,0 +1,129 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemFromErrors(t *testing.T) {
+	errOther := errors.New("unexpected EOF")
+	custom := NewProblem(http.StatusConflict, "conflict")
+	_, errQuality := ParseAccept("application/json;q=abc")
+
+	testio := []struct {
+		err         error
+		negotiation int
+		unmarshal   int
+	}{
+		{ErrNoContentType, http.StatusNotAcceptable, http.StatusUnsupportedMediaType},
+		{ErrInvalidMediaRange, http.StatusBadRequest, http.StatusBadRequest},
+		{ErrInvalidAcceptParam, http.StatusBadRequest, http.StatusBadRequest},
+		{errQuality, http.StatusBadRequest, http.StatusBadRequest},
+		{ErrInvalidMediaParam, http.StatusInternalServerError, http.StatusBadRequest},
+		{errOther, http.StatusInternalServerError, http.StatusBadRequest},
+		{custom, http.StatusConflict, http.StatusConflict},
+	}
+
+	for _, test := range testio {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			p := NegotiationProblem(test.err)
+			assert.Equal(t, test.negotiation, p.Status)
+			assert.Equal(t, http.StatusText(test.negotiation), p.Title)
+
+			p = UnmarshalProblem(test.err)
+			assert.Equal(t, test.unmarshal, p.Status)
+			assert.Equal(t, http.StatusText(test.unmarshal), p.Title)
+		})
+	}
+}
+
+func TestProblemMarshalJSON(t *testing.T) {
+	p := NewProblem(http.StatusForbidden, "Your current balance is 30, but that costs 50.")
+	p.Type = "https://example.com/probs/out-of-credit"
+	p.Instance = "/account/12345/msgs/abc"
+	p.Extensions["balance"] = 30
+
+	data, err := json.Marshal(p)
+	assert.Nil(t, err)
+
+	var members map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &members))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "https://example.com/probs/out-of-credit",
+		"title":    "Forbidden",
+		"status":   float64(403),
+		"detail":   "Your current balance is 30, but that costs 50.",
+		"instance": "/account/12345/msgs/abc",
+		"balance":  float64(30),
+	}, members)
+}
+
+func TestProblemMarshalXML(t *testing.T) {
+	p := NewProblem(http.StatusUnprocessableEntity, "bad")
+	p.Extensions["invalid-params"] = []map[string]string{
+		{"name": "age", "reason": "must be a positive integer"},
+	}
+	p.Extensions["fields"] = map[string]string{"b": "2", "a": "1"}
+
+	data, err := xml.Marshal(p)
+	assert.Nil(t, err)
+	assert.Equal(t, `<problem xmlns="urn:ietf:rfc:7807"><detail>bad</detail>`+
+		`<fields><a>1</a><b>2</b></fields>`+
+		`<invalid-params><i><name>age</name><reason>must be a positive integer</reason></i></invalid-params>`+
+		`<status>422</status><title>Unprocessable Entity</title><type>about:blank</type></problem>`,
+		string(data))
+}
+
+func TestWriteProblemRenderError(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(AcceptHeaderKey, ProblemXMLMediaType)
+	w := httptest.NewRecorder()
+
+	p := NewProblem(http.StatusUnprocessableEntity, "bad")
+	p.Extensions["callback"] = func() {}
+	assert.NotNil(t, WriteProblem(w, req, p))
+	assert.False(t, w.Flushed)
+	assert.Equal(t, 0, w.Body.Len())
+	assert.Empty(t, w.Header().Get(ContentTypeHeader))
+}
+
+func TestWriteProblem(t *testing.T) {
+	testio := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", ProblemJSONMediaType,
+			`{"detail":"gone","status":410,"title":"Gone","type":"about:blank"}` + "\n"},
+		{"text/html", ProblemJSONMediaType,
+			`{"detail":"gone","status":410,"title":"Gone","type":"about:blank"}` + "\n"},
+		{"application/xml", "application/xml",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<problem xmlns="urn:ietf:rfc:7807"><detail>gone</detail><status>410</status>` +
+				`<title>Gone</title><type>about:blank</type></problem>`},
+		{"application/problem+json;q=0.5, application/problem+xml", ProblemXMLMediaType,
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<problem xmlns="urn:ietf:rfc:7807"><detail>gone</detail><status>410</status>` +
+				`<title>Gone</title><type>about:blank</type></problem>`},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			w := httptest.NewRecorder()
+
+			err := WriteProblem(w, req, NewProblem(http.StatusGone, "gone"))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusGone, w.Code)
+			assert.Equal(t, test.contentType, w.Header().Get(ContentTypeHeader))
+			assert.Equal(t, test.body, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package negotiator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// LinkHeader is the constant value for the key indicating the Link header
+const LinkHeader = "Link"
+
+var (
+	// ErrMultipleChoices is the error returned by NegotiateReactive when more
+	// than one registered representation is equally acceptable, and a 300
+	// Multiple Choices response has been written
+	ErrMultipleChoices = errors.New("Multiple Choices")
+)
+
+// Alternate is a single representation of a resource offered to a client as
+// part of agent-driven negotiation
+type Alternate struct {
+	MediaType MediaType `json:"type"`
+	URI       string    `json:"href"`
+}
+
+// AlternateURIFunc returns the URI at which the representation of the
+// requested resource with the provided media type can be retrieved directly
+type AlternateURIFunc func(req *http.Request, mediaType MediaType) string
+
+// multipleChoicesHTML is the template used to render 300 Multiple Choices
+// responses for clients accepting HTML
+var multipleChoicesHTML = template.Must(template.New("300").Parse(`<!DOCTYPE html>
+<html>
+<head><title>300 Multiple Choices</title></head>
+<body>
+<h1>Multiple Choices</h1>
+<ul>
+{{range .}}<li><a href="{{.URI}}">{{.MediaType}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// choicesFormat is a media type a 300 Multiple Choices response body may be
+// rendered as, along with the function which renders it
+type choicesFormat struct {
+	mediaType MediaType
+	render    func(io.Writer, []Alternate) error
+}
+
+// formatType returns the media type of the choices format
+func (f choicesFormat) formatType() MediaType {
+	return f.mediaType
+}
+
+// choicesFormats is the Registry used to negotiate the format of 300 Multiple
+// Choices response bodies. HTML is used when none of the other formats are
+// acceptable
+var choicesFormats = newFormatRegistry(
+	choicesFormat{"text/html", renderChoicesHTML},
+	choicesFormat{"application/json", renderChoicesJSON},
+	choicesFormat{"text/plain", renderChoicesText},
+)
+
+// renderChoicesHTML renders the alternates as an HTML page of hyperlinks
+func renderChoicesHTML(w io.Writer, alternates []Alternate) error {
+	return multipleChoicesHTML.Execute(w, alternates)
+}
+
+// renderChoicesJSON renders the alternates as a JSON array
+func renderChoicesJSON(w io.Writer, alternates []Alternate) error {
+	return json.NewEncoder(w).Encode(alternates)
+}
+
+// renderChoicesText renders the alternates as plain text, one per line
+func renderChoicesText(w io.Writer, alternates []Alternate) error {
+	for _, alt := range alternates {
+		if _, err := fmt.Fprintf(w, "%s %s\n", alt.MediaType, alt.URI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultipleChoices writes a 300 Multiple Choices response listing the provided
+// alternate representations, as defined by RFC-7231. Each alternate is
+// advertised in a Link header with an "alternate" relation, and listed in a
+// response body rendered as HTML, JSON, or plain text, whichever is best
+// supported by the request's Accept header
+func MultipleChoices(w http.ResponseWriter, req *http.Request, alternates []Alternate) error {
+	for _, alt := range alternates {
+		w.Header().Add(LinkHeader,
+			fmt.Sprintf("<%s>; rel=\"alternate\"; type=%q", alt.URI, string(alt.MediaType)))
+	}
+
+	f, _ := negotiateFormat(choicesFormats, req)
+	format := f.(choicesFormat)
+	AddVary(w.Header(), AcceptHeaderKey)
+	w.Header().Set(ContentTypeHeader, contentTypeFor(format.mediaType))
+	w.WriteHeader(http.StatusMultipleChoices)
+	return format.render(w, alternates)
+}
+
+// NegotiateReactive negotiates the request's Accept header like Negotiate,
+// but opts in to agent-driven negotiation. If more than one registered
+// representation is equally acceptable to media ranges the client explicitly
+// named, a 300 Multiple Choices response listing each of them, at the URI
+// returned by the provided AlternateURIFunc, is written to w and
+// ErrMultipleChoices is returned. Representations which are only equally
+// acceptable to wildcard media ranges, such as */*, are left for the server to
+// choose between, as are all representations for a request without an Accept
+// header. The first of them to be registered is selected. Representations
+// whose alternate URIs are identical can not be told apart by the client, so
+// only the first of them is listed, and it is selected if no others remain
+func (r *Registry) NegotiateReactive(w http.ResponseWriter, req *http.Request, uri AlternateURIFunc) (interface{}, *Accept, error) {
+	AddVary(w.Header(), AcceptHeaderKey)
+	choices, err := r.negotiate(acceptOrAny(req.Header.Get(AcceptHeaderKey)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	explicit := make([]*candidate, 0, len(choices))
+	for _, c := range choices {
+		if !c.accept.MediaRange.IsWildcard() {
+			explicit = append(explicit, c)
+		}
+	}
+	switch len(explicit) {
+	case 0:
+		return choices[0].value(), choices[0].accept, nil
+	case 1:
+		return explicit[0].value(), explicit[0].accept, nil
+	}
+
+	alternates := make([]Alternate, 0, len(explicit))
+	seen := make(map[string]bool, len(explicit))
+	for _, c := range explicit {
+		mediaType := c.reg.fullType()
+		alt := Alternate{MediaType: mediaType, URI: uri(req, mediaType)}
+		if !seen[alt.URI] {
+			seen[alt.URI] = true
+			alternates = append(alternates, alt)
+		}
+	}
+	if len(alternates) == 1 {
+		return explicit[0].value(), explicit[0].accept, nil
+	}
+	if err := MultipleChoices(w, req, alternates); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, ErrMultipleChoices
+}
@@ -0,0 +1,172 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func formatURI(req *http.Request, mediaType MediaType) string {
+	return req.URL.Path + "?format=" + url.QueryEscape(mediaType.SubType())
+}
+
+func TestRegistryChoices(t *testing.T) {
+	testio := []struct {
+		inp    string
+		legacy []MediaType
+		strict []MediaType
+	}{
+		{"application/json", []MediaType{"application/json"}, []MediaType{"application/json"}},
+		{"*/*", []MediaType{"application/json", "application/xml", "text/csv"},
+			[]MediaType{"application/json", "application/xml", "text/csv"}},
+		{"application/*, text/csv;q=0.8",
+			[]MediaType{"application/json", "application/xml"},
+			[]MediaType{"application/json", "application/xml"}},
+		{"application/*;q=0.5, text/csv;q=0.5",
+			[]MediaType{"application/json", "application/xml"},
+			[]MediaType{"text/csv"}},
+	}
+
+	legacy := NewRegistry()
+	strict := NewRegistryWithPolicy(StrictPolicy)
+	for _, reg := range []*Registry{legacy, strict} {
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xml", testGeneric{})
+		reg.Register("text/csv", testSpecific{})
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			choices, err := legacy.Choices(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.legacy, choices)
+
+			choices, err = strict.Choices(test.inp)
+			assert.Nil(t, err)
+			assert.Equal(t, test.strict, choices)
+		})
+	}
+
+	_, err := strict.Choices("image/png")
+	assert.Equal(t, ErrNoContentType, err)
+}
+
+func TestNegotiateReactive(t *testing.T) {
+	testReg := NewRegistryWithPolicy(StrictPolicy)
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("text/csv", testSpecific{})
+
+	testio := []struct {
+		accept   string
+		expected interface{}
+		err      error
+		links    []string
+		body     string
+	}{
+		{"text/csv", testSpecific{}, nil, nil, ""},
+		{"image/png", nil, ErrNoContentType, nil, ""},
+		{"*/*", testGeneric{}, nil, nil, ""},
+		{"", testGeneric{}, nil, nil, ""},
+		{"text/*, application/*", testGeneric{}, nil, nil, ""},
+		{"text/csv;q=0.5, */*;q=0.5", testSpecific{}, nil, nil, ""},
+		{"application/json, text/csv, text/html", nil, ErrMultipleChoices,
+			[]string{`</messages?format=json>; rel="alternate"; type="application/json"`,
+				`</messages?format=csv>; rel="alternate"; type="text/csv"`},
+			"<li><a href=\"/messages?format=json\">application/json</a></li>\n" +
+				"<li><a href=\"/messages?format=csv\">text/csv</a></li>\n"},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/messages", nil)
+			if len(test.accept) > 0 {
+				req.Header.Set(AcceptHeaderKey, test.accept)
+			}
+			w := httptest.NewRecorder()
+
+			i, _, err := testReg.NegotiateReactive(w, req, formatURI)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, i)
+			if test.err != ErrMultipleChoices {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Empty(t, w.Header()[LinkHeader])
+				assert.Equal(t, 0, w.Body.Len())
+				return
+			}
+			assert.Equal(t, http.StatusMultipleChoices, w.Code)
+			assert.Equal(t, test.links, w.Header()[LinkHeader])
+			assert.Equal(t, "text/html; charset=utf-8", w.Header().Get(ContentTypeHeader))
+			assert.Contains(t, w.Body.String(), test.body)
+		})
+	}
+}
+
+func TestNegotiateReactiveAlternateURI(t *testing.T) {
+	testReg := NewRegistryWithPolicy(StrictPolicy)
+	testReg.Register("application/json; version=1", testGeneric{})
+	testReg.Register("application/json; version=2", testSpecific{})
+
+	testio := []struct {
+		name     string
+		v2       bool
+		expected interface{}
+		err      error
+		links    []string
+	}{
+		{"shared format", false, testGeneric{}, nil, nil},
+		{"registered format", true, nil, ErrMultipleChoices,
+			[]string{`</messages?format=json>; rel="alternate"; type="application/json;version=1"`,
+				`</messages?format=v2>; rel="alternate"; type="application/json;version=2"`}},
+	}
+
+	for _, test := range testio {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewFormatOverride()
+			if test.v2 {
+				f.Register("v2", "application/json; version=2")
+			}
+			req := httptest.NewRequest("GET", "http://example.com/messages", nil)
+			req.Header.Set(AcceptHeaderKey, "application/json;version=1, application/json;version=2")
+			w := httptest.NewRecorder()
+
+			i, _, err := testReg.NegotiateReactive(w, req, f.AlternateURI)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, i)
+			assert.Equal(t, test.links, w.Header()[LinkHeader])
+		})
+	}
+}
+
+func TestMultipleChoicesFormats(t *testing.T) {
+	alternates := []Alternate{
+		{MediaType: "application/json", URI: "/messages.json"},
+		{MediaType: "text/csv", URI: "/messages.csv"},
+	}
+
+	testio := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"application/json", "application/json",
+			`[{"type":"application/json","href":"/messages.json"},{"type":"text/csv","href":"/messages.csv"}]` + "\n"},
+		{"text/plain", "text/plain; charset=utf-8",
+			"application/json /messages.json\ntext/csv /messages.csv\n"},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/messages", nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			w := httptest.NewRecorder()
+
+			assert.Nil(t, MultipleChoices(w, req, alternates))
+			assert.Equal(t, http.StatusMultipleChoices, w.Code)
+			assert.Equal(t, test.contentType, w.Header().Get(ContentTypeHeader))
+			assert.Equal(t, test.body, w.Body.String())
+		})
+	}
+}
@@ -2,8 +2,8 @@ package negotiator
 
 import (
 	"errors"
-	"mime"
 	"reflect"
+	"strings"
 )
 
 var (
@@ -16,53 +16,509 @@ var (
 // representing any parameters passed to the Content-Type header
 type ContentTypeParams map[string]string
 
+// registration is a single media type registered in a Registry, along with the
+// parameters it declares support for, and its default value. acceptParams is
+// nil unless the accept-params the registration understands are declared, and
+// extensions holds the accept-ext it declares support for
+type registration struct {
+	mediaType    MediaType
+	params       MediaParams
+	value        interface{}
+	acceptParams map[string]bool
+	extensions   map[string]bool
+	negotiator   ExtensionNegotiator
+}
+
+// newRegistration parses a registered content type, including any of its
+// declared parameters (e.g application/json;profile="urn:x:v2"). Content types
+// that can not be parsed are registered verbatim, without any parameters. If
+// the value, or a pointer to it, implements ExtensionNegotiator it is resolved
+// once here, rather than during every negotiation
+func newRegistration(contentType string, value interface{}) *registration {
+	negotiator, _ := pointerTo(value).(ExtensionNegotiator)
+	mediaType, err := ParseMediaType(contentType)
+	if err != nil {
+		return &registration{mediaType: MediaType(contentType),
+			params:     make(MediaParams),
+			value:      value,
+			negotiator: negotiator}
+	}
+
+	params := mediaType.Params()
+	if params == nil {
+		params = make(MediaParams)
+	}
+	return &registration{mediaType: mediaType.Base(),
+		params:     params,
+		value:      value,
+		negotiator: negotiator}
+}
+
+// fullType returns the registered media type, including its declared
+// parameters
+func (reg *registration) fullType() MediaType {
+	if len(reg.params) == 0 {
+		return reg.mediaType
+	}
+	return NewMediaType(reg.mediaType.Type(), reg.mediaType.SubType(), reg.params)
+}
+
+// sameAs returns true if both registrations declare the same media type and
+// parameters
+func (reg *registration) sameAs(other *registration) bool {
+	return reg.mediaType == other.mediaType &&
+		reflect.DeepEqual(reg.params, other.params)
+}
+
+// matchParams reports whether the provided parameters are compatible with the
+// parameters declared by the registration, and how many of them matched. A
+// parameter the registration does not declare is ignored, while a declared
+// parameter with a conflicting value excludes the registration entirely
+func (reg *registration) matchParams(params MediaParams) (int, bool) {
+	var matched int
+	for key, val := range params {
+		declared, ok := reg.params[key]
+		if !ok {
+			continue
+		}
+		if declared != val {
+			return 0, false
+		}
+		matched++
+	}
+	return matched, true
+}
+
+// candidate is a registration matched by a single media range, along with how
+// well the media range's parameters matched the registration. val holds the
+// value returned by an ExtensionNegotiator, if the registration's value is one
+type candidate struct {
+	reg       *registration
+	accept    *Accept
+	matched   int
+	unmatched int
+	val       interface{}
+}
+
+// newCandidate matches a registration against a media range and its
+// parameters, returning nil if they are incompatible
+func newCandidate(reg *registration, media MediaType, params MediaParams) *candidate {
+	if !media.Contains(reg.mediaType) {
+		return nil
+	}
+
+	matched, ok := reg.matchParams(params)
+	if !ok {
+		return nil
+	}
+	return &candidate{reg: reg,
+		matched:   matched,
+		unmatched: len(reg.params) - matched}
+}
+
+// betterThan returns true if the candidate matched its media range more
+// precisely than the other candidate. Candidates matching more parameters are
+// preferred, followed by those declaring the fewest unmatched parameters
+func (c *candidate) betterThan(other *candidate) bool {
+	if other == nil {
+		return true
+	} else if c.matched != other.matched {
+		return c.matched > other.matched
+	}
+	return c.unmatched < other.unmatched
+}
+
+// specificity returns the specificity of the candidate's media range with
+// respect to its registration. Only the accept-params the registration
+// matched are counted, as those it does not declare do not apply to it
+func (c *candidate) specificity() int {
+	if s := specificity(c.accept); s < 2 {
+		return s
+	}
+	return 2 + c.matched
+}
+
+// ignored returns the number of accept-params of the candidate's media range
+// which the registration does not declare
+func (c *candidate) ignored() int {
+	return len(c.accept.AcceptParams) - c.matched
+}
+
+// moreSpecificThan returns true if the candidate's media range is more
+// specific than that of the other candidate, with respect to its registration.
+// Media ranges of equal specificity are ranked by the fewest accept-params
+// which do not apply to the registration
+func (c *candidate) moreSpecificThan(other *candidate) bool {
+	if other == nil {
+		return true
+	} else if s1, s2 := c.specificity(), other.specificity(); s1 != s2 {
+		return s1 > s2
+	} else if i1, i2 := c.ignored(), other.ignored(); i1 != i2 {
+		return i1 < i2
+	}
+	return c.betterThan(other)
+}
+
+// preferredTo returns true if the candidate's media range has a higher quality
+// than that of the other candidate, using the specificity of the media range
+// to break ties
+func (c *candidate) preferredTo(other *candidate) bool {
+	if other == nil {
+		return true
+	} else if c.accept.Quality != other.accept.Quality {
+		return c.accept.Quality > other.accept.Quality
+	}
+	return c.moreSpecificThan(other)
+}
+
+// value returns a copy of the candidate registration's default value, or the
+// value returned by its ExtensionNegotiator
+func (c *candidate) value() interface{} {
+	if c.val != nil {
+		return c.val
+	}
+	return reflect.ValueOf(c.reg.value).Interface()
+}
+
+// An ExtensionNegotiator is a value registered in a Registry which inspects the
+// accept-ext of the media ranges matching it during negotiation (e.g
+// application/json;q=1;version=2). A value implementing ExtensionNegotiator
+// supports every accept-ext, including those required by a NegotiationPolicy.
+// NegotiateExtensions is called on a single copy of the registered value,
+// which is shared by concurrent negotiations and must not be modified
+type ExtensionNegotiator interface {
+	// NegotiateExtensions returns the value negotiated for the provided
+	// Accept, which may be tailored to its accept-ext, or false if the value
+	// can not satisfy its accept-ext and should not match it. The Accept
+	// must not be retained, as it may be reused once negotiation completes
+	NegotiateExtensions(acpt *Accept) (interface{}, bool)
+}
+
 // Registry is a content type registry used for managing a mapping of media
 // ranges to the interfaces that represent those resources
-type Registry map[string]interface{}
+type Registry struct {
+	registrations []*registration
+	policy        NegotiationPolicy
+	formats       *FormatOverride
+	cache         *negotiationCache
+	index         *registryIndex
+}
 
-// NewRegistry returns an empty Registry
+// NewRegistry returns an empty Registry using the LegacyPolicy
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{policy: LegacyPolicy}
+}
+
+// NewRegistryWithPolicy returns an empty Registry which negotiates accept
+// headers according to the provided NegotiationPolicy
+func NewRegistryWithPolicy(policy NegotiationPolicy) *Registry {
+	return &Registry{policy: policy}
+}
+
+// Policy returns the NegotiationPolicy used by the Registry
+func (r *Registry) Policy() NegotiationPolicy {
+	return r.policy
 }
 
 // Register registers the default struct value for a content type in the
 // registry. when requested, a copy of the default value will be provided as
-// the result of a call to Negotiate
-func (r Registry) Register(contentType string, defaultValue interface{}) {
+// the result of a call to Negotiate. The content type may declare the
+// parameters it supports (e.g application/json;version=2), which are then
+// used to match, filter and rank the accept-params of a request
+func (r *Registry) Register(contentType string, defaultValue interface{}) {
 	if reflect.TypeOf(defaultValue).Kind() == reflect.Ptr {
-		r[contentType] = reflect.ValueOf(defaultValue).Elem().Interface()
-		return
+		defaultValue = reflect.ValueOf(defaultValue).Elem().Interface()
+	}
+
+	reg := newRegistration(contentType, defaultValue)
+	for i, existing := range r.registrations {
+		if existing.sameAs(reg) {
+			r.registrations[i] = reg
+			r.invalidate()
+			return
+		}
 	}
-	r[contentType] = defaultValue
+	r.add(reg)
+	r.invalidate()
+}
+
+// MediaTypes returns the media types registered in the Registry, including any
+// of their declared parameters, in the order in which they were registered
+func (r *Registry) MediaTypes() []MediaType {
+	mediaTypes := make([]MediaType, 0, len(r.registrations))
+	for _, reg := range r.registrations {
+		mediaTypes = append(mediaTypes, reg.fullType())
+	}
+	return mediaTypes
+}
+
+// Select returns a new Registry, using the same NegotiationPolicy,
+// FormatOverride and cache size, which contains only the registrations whose media type
+// satisfies the provided function. This allows negotiation rules to be written
+// against subsets of a Registry, such as all media types belonging to a
+// vendor's facet:
+//
+//	zones := registry.Select(func(m MediaType) bool {
+//		return m.BelongsTo(VendorTree, "dyn")
+//	})
+func (r *Registry) Select(fn func(MediaType) bool) *Registry {
+	selected := NewRegistryWithPolicy(r.policy)
+	selected.formats = r.formats
+	if r.cache != nil {
+		selected.SetCacheSize(r.cache.size)
+	}
+	for _, reg := range r.registrations {
+		if fn(reg.fullType()) {
+			copied := *reg
+			selected.add(&copied)
+		}
+	}
+	return selected
+}
+
+// DeclareAcceptParams declares the accept-params understood by the
+// registration of the provided content type, which a NegotiationPolicy may
+// use to reject media ranges with unknown accept-params. ErrNoContentType is
+// returned if the content type is not registered
+func (r *Registry) DeclareAcceptParams(contentType string, params ...string) error {
+	reg := r.registration(contentType)
+	if reg == nil {
+		return ErrNoContentType
+	}
+	reg.acceptParams = declaredNames(params)
+	r.invalidate()
+	return nil
+}
+
+// DeclareExtensions declares the accept-ext supported by the registration of
+// the provided content type, which a NegotiationPolicy may require.
+// ErrNoContentType is returned if the content type is not registered
+func (r *Registry) DeclareExtensions(contentType string, extensions ...string) error {
+	reg := r.registration(contentType)
+	if reg == nil {
+		return ErrNoContentType
+	}
+	reg.extensions = declaredNames(extensions)
+	r.invalidate()
+	return nil
+}
+
+// registration returns the registration of the provided content type,
+// including its declared parameters, or nil if it is not registered
+func (r *Registry) registration(contentType string) *registration {
+	declared := newRegistration(contentType, nil)
+	for _, reg := range r.registrations {
+		if reg.sameAs(declared) {
+			return reg
+		}
+	}
+	return nil
+}
+
+// declaredNames returns the set of the provided case insensitive names
+func declaredNames(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
+
+// match finds the registration which best matches the provided media range
+// and parameters. Registrations matching more of the provided parameters are
+// preferred, followed by those declaring the fewest unmatched parameters, and
+// finally by the order in which they were registered
+func (r *Registry) match(media MediaType, params MediaParams) *candidate {
+	if matches := r.matches(media, params); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// matches returns every registration tied as the best match for the provided
+// media range and parameters, in the order in which they were registered
+func (r *Registry) matches(media MediaType, params MediaParams) []*candidate {
+	return r.best(media, func(reg *registration) *candidate {
+		return newCandidate(reg, media, params)
+	})
+}
+
+// acceptCandidate matches a registration against a media range of an accept
+// header, returning nil if they are incompatible, if the Registry's
+// NegotiationPolicy rejects an accept-param the registration does not
+// understand or an accept-ext it does not support, or if the registration's
+// ExtensionNegotiator rejects the media range's accept-ext
+func (r *Registry) acceptCandidate(reg *registration, hdr *Accept) *candidate {
+	if !r.policy.understands(reg, hdr.AcceptParams) {
+		return nil
+	}
+	if reg.negotiator == nil && !r.policy.supportsExtensions(reg, hdr.AcceptExt) {
+		return nil
+	}
+
+	c := newCandidate(reg, hdr.MediaRange, hdr.AcceptParams)
+	if c == nil {
+		return nil
+	}
+	c.accept = hdr
+	if reg.negotiator != nil {
+		val, ok := reg.negotiator.NegotiateExtensions(hdr)
+		if !ok {
+			return nil
+		}
+		c.val = val
+	}
+	return c
+}
+
+// best returns every candidate, as returned by the provided function for each
+// registration which may be included in the provided media range, tied as the
+// best match, in the order in which they were registered
+func (r *Registry) best(media MediaType, fn func(*registration) *candidate) []*candidate {
+	var best []*candidate
+	r.each(media, func(_ int, reg *registration) {
+		c := fn(reg)
+		if c == nil {
+			return
+		}
+		if len(best) == 0 || c.betterThan(best[0]) {
+			best = []*candidate{c}
+		} else if !best[0].betterThan(c) {
+			best = append(best, c)
+		}
+	})
+	return best
 }
 
 // Negotiate attempts to negotiate the proper interface for the provided accept
 // header. Negotiate returns a copy of the default interface that best matches
-// the provided accept header, if a match is found
-func (r Registry) Negotiate(header string) (interface{}, *Accept, error) {
-	acceptHeader, err := ParseHeader(header)
+// the provided accept header, if a match is found. The accept header is parsed
+// and ordered according to the Registry's NegotiationPolicy
+func (r *Registry) Negotiate(header string) (interface{}, *Accept, error) {
+	choices, err := r.negotiate(header)
 	if err != nil {
 		return nil, nil, err
 	}
+	return choices[0].value(), choices[0].accept, nil
+}
+
+// Choices returns the media types of every registration tied as the best
+// match for the provided accept header, in the order in which they were
+// registered. ErrNoContentType is returned if no registration matches
+func (r *Registry) Choices(header string) ([]MediaType, error) {
+	choices, err := r.negotiate(header)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaTypes := make([]MediaType, 0, len(choices))
+	for _, c := range choices {
+		mediaTypes = append(mediaTypes, c.reg.fullType())
+	}
+	return mediaTypes, nil
+}
+
+// negotiate parses the provided accept header according to the Registry's
+// NegotiationPolicy, and returns every registration tied as its best match.
+// Results are memoized if the Registry's cache is enabled
+func (r *Registry) negotiate(header string) ([]*candidate, error) {
+	if r.cache == nil {
+		return r.negotiateHeader(header)
+	}
+
+	choices, err, ok := r.cache.get(header)
+	if !ok {
+		choices, err = r.negotiateHeader(header)
+		r.cache.put(header, choices, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cloneCandidates(choices), nil
+}
+
+// negotiateHeader implements negotiate without consulting the cache. The
+// accept header is parsed into a pooled headerBuffer, and the Accepts of the
+// chosen candidates are copied before it is released
+func (r *Registry) negotiateHeader(header string) ([]*candidate, error) {
+	buf := headerBuffers.Get().(*headerBuffer)
+	defer headerBuffers.Put(buf)
+
+	acceptHeader, err := r.policy.parseHeader(header, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var choices []*candidate
+	if r.policy.StrictQuality {
+		choices = r.negotiateStrict(acceptHeader)
+	} else {
+		choices = r.negotiateLegacy(acceptHeader)
+	}
 
+	if len(choices) == 0 {
+		return nil, ErrNoContentType
+	}
+	cloneAccepts(choices)
+	return choices, nil
+}
+
+// negotiateLegacy returns the best registrations matching the first media
+// range of the sorted accept header which matches any registration
+func (r *Registry) negotiateLegacy(acceptHeader AcceptHeader) []*candidate {
 	for _, hdr := range acceptHeader {
-		if val, ok := r[string(hdr.MediaRange)]; ok {
-			return reflect.ValueOf(val).Interface(), hdr, nil
+		matches := r.best(hdr.MediaRange, func(reg *registration) *candidate {
+			return r.acceptCandidate(reg, hdr)
+		})
+		if len(matches) > 0 {
+			return matches
+		}
+	}
+	return nil
+}
+
+// negotiateStrict implements RFC-7231 negotiation. The quality of each
+// registration is that of the most specific media range matching it, and the
+// registration with the highest non-zero quality is selected. Ties are broken
+// by the specificity of the matching media range, then by how precisely the
+// media range's parameters matched, and finally by registration order
+func (r *Registry) negotiateStrict(acceptHeader AcceptHeader) []*candidate {
+	matches := make(map[int]*candidate)
+	for _, hdr := range acceptHeader {
+		r.each(hdr.MediaRange, func(pos int, reg *registration) {
+			c := r.acceptCandidate(reg, hdr)
+			if c != nil && c.moreSpecificThan(matches[pos]) {
+				matches[pos] = c
+			}
+		})
+	}
+
+	var best []*candidate
+	for _, pos := range sortedPositions(matches) {
+		match := matches[pos]
+		if !r.policy.acceptable(match.accept) {
+			continue
+		}
+		if len(best) == 0 || match.preferredTo(best[0]) {
+			best = []*candidate{match}
+		} else if !best[0].preferredTo(match) {
+			best = append(best, match)
 		}
 	}
-	return nil, nil, ErrNoContentType
+	return best
 }
 
 // ContentType parses the provided Content-Type header and attempts to find an
 // interface which implements the specified content type
-func (r Registry) ContentType(header string) (interface{}, ContentTypeParams, error) {
-	mediaType, params, err := mime.ParseMediaType(header)
+func (r *Registry) ContentType(header string) (interface{}, ContentTypeParams, error) {
+	contentType, err := ParseContentType(header)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if val, ok := r[mediaType]; ok {
-		return reflect.ValueOf(val).Interface(), params, nil
+	c := r.match(contentType.MediaType, MediaParams(contentType.Params))
+	if c == nil {
+		return nil, nil, ErrNoContentType
 	}
-	return nil, nil, ErrNoContentType
+	return c.value(), contentType.Params, nil
 }
@@ -1,8 +1,11 @@
 package negotiator
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -79,8 +82,7 @@ func TestRegistryContentType(t *testing.T) {
 	}{
 		{"application/json", testGeneric{}, ""},
 		{"application/xml", nil, ErrNoContentType.Error()},
-		{"application/xml/json/ foobar", nil,
-			"mime: unexpected content after media subtype"},
+		{"application/xml/json/ foobar", nil, ErrInvalidMediaRange.Error()},
 	}
 
 	var i interface{}
@@ -105,3 +107,227 @@ func TestRegistryContentType(t *testing.T) {
 		}
 	}
 }
+
+type testProfileV1 struct {
+	X int
+}
+
+type testProfileV2 struct {
+	X int
+	Y int
+}
+
+func TestRegistryNegotiateParams(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register(`application/json;profile="urn:x:v1"`, testProfileV1{})
+	testReg.Register(`application/json;profile="urn:x:v2"`, testProfileV2{})
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("application/xml;version=2", testSpecific{})
+
+	testio := []struct {
+		inp      string
+		expected interface{}
+		err      error
+	}{
+		{`application/json;profile="urn:x:v2"`, testProfileV2{}, nil},
+		{`application/json;profile="urn:x:v1"`, testProfileV1{}, nil},
+		{`application/json;profile=urn:x:v1`, testProfileV1{}, nil},
+		{`application/json;profile="urn:x:v3"`, testGeneric{}, nil},
+		{"application/json", testGeneric{}, nil},
+		{"application/json;indent=4", testGeneric{}, nil},
+		{"application/xml;version=2", testSpecific{}, nil},
+		{"application/xml", testSpecific{}, nil},
+		{"application/xml;version=1", nil, ErrNoContentType},
+		{`application/xml;version=1, application/json;profile="urn:x:v1";q=0.5`,
+			testProfileV1{}, nil},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := testReg.Negotiate(test.inp)
+			if err != test.err {
+				t.Errorf("Expected error %v, got %v instead", test.err, err)
+			}
+			if i != test.expected {
+				t.Errorf("Expected %#v, got %#v instead", test.expected, i)
+			}
+		})
+	}
+}
+
+func TestRegistryContentTypeParams(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register("application/json;version=1", testProfileV1{})
+	testReg.Register("application/json;version=2", testProfileV2{})
+
+	testio := []struct {
+		inp      string
+		expected interface{}
+		err      error
+	}{
+		{"application/json;version=2", testProfileV2{}, nil},
+		{"application/json; version=1; charset=utf-8", testProfileV1{}, nil},
+		{"application/json", testProfileV1{}, nil},
+		{"application/json;version=3", nil, ErrNoContentType},
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := testReg.ContentType(test.inp)
+			if err != test.err {
+				t.Errorf("Expected error %v, got %v instead", test.err, err)
+			}
+			if i != test.expected {
+				t.Errorf("Expected %#v, got %#v instead", test.expected, i)
+			}
+		})
+	}
+}
+
+func TestRegistryReregister(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("application/json", testSpecific{})
+
+	i, _, err := testReg.Negotiate("application/json")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if i != (testSpecific{}) {
+		t.Errorf("Expected re-registration to replace %#v, got %#v", testSpecific{}, i)
+	}
+}
+
+func TestRegistryNegotiateWildcards(t *testing.T) {
+	testio := []struct {
+		inp    string
+		legacy interface{}
+		strict interface{}
+	}{
+		{"application/*", testGeneric{}, testGeneric{}},
+		{"*/*", testGeneric{}, testGeneric{}},
+		{"text/*", testProfileV1{}, testProfileV1{}},
+		{"application/*+xml", testSpecific{}, testSpecific{}},
+		{"text/*;q=0.3, text/html;q=0, application/xhtml+xml;q=0.2",
+			testProfileV1{}, testProfileV2{}},
+		{"text/html;level=1;q=0, text/html;q=0.5, text/plain;q=0.4",
+			testProfileV1{}, testProfileV1{}},
+		{"text/html;level=1;q=0.5, text/html;q=0, text/plain;q=0.4",
+			testProfileV1{}, testProfileV2{}},
+	}
+
+	legacy := NewRegistry()
+	strict := NewRegistryWithPolicy(StrictPolicy)
+	for _, reg := range []*Registry{legacy, strict} {
+		reg.Register("application/json", testGeneric{})
+		reg.Register("application/xhtml+xml", testSpecific{})
+		reg.Register("text/html", testProfileV1{})
+		reg.Register("text/plain", testProfileV2{})
+	}
+
+	for _, test := range testio {
+		t.Run(test.inp, func(t *testing.T) {
+			i, _, err := legacy.Negotiate(test.inp)
+			if err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if i != test.legacy {
+				t.Errorf("Expected %#v, got %#v instead", test.legacy, i)
+			}
+
+			i, _, err = strict.Negotiate(test.inp)
+			if err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if i != test.strict {
+				t.Errorf("Expected %#v, got %#v instead", test.strict, i)
+			}
+		})
+	}
+}
+
+func TestRegistrySelect(t *testing.T) {
+	testReg := NewRegistryWithPolicy(StrictPolicy)
+	testReg.Register("application/json", testGeneric{})
+	testReg.Register("application/vnd.dyn.zone+json", testSpecific{})
+	testReg.Register("application/vnd.dyn.record+json;version=2", testProfileV2{})
+	testReg.Register("application/vnd.github+json", testProfileV1{})
+
+	assert.Equal(t, []MediaType{
+		"application/json",
+		"application/vnd.dyn.zone+json",
+		"application/vnd.dyn.record+json;version=2",
+		"application/vnd.github+json",
+	}, testReg.MediaTypes())
+
+	dyn := testReg.Select(func(m MediaType) bool {
+		return m.BelongsTo(VendorTree, "dyn")
+	})
+	assert.Equal(t, StrictPolicy, dyn.Policy())
+	assert.Equal(t, []MediaType{
+		"application/vnd.dyn.zone+json",
+		"application/vnd.dyn.record+json;version=2",
+	}, dyn.MediaTypes())
+
+	i, _, err := dyn.Negotiate("application/json, application/*+json;q=0.5")
+	assert.Nil(t, err)
+	assert.Equal(t, testSpecific{}, i)
+
+	_, _, err = dyn.Negotiate("application/vnd.github+json")
+	assert.Equal(t, ErrNoContentType, err)
+}
+
+// testVersioned implements ExtensionNegotiator, negotiating the version
+// requested by a media range's version accept-ext
+type testVersioned struct {
+	Version int
+}
+
+func (v *testVersioned) NegotiateExtensions(a *Accept) (interface{}, bool) {
+	switch a.AcceptExt["version"] {
+	case "", "1":
+		return testVersioned{1}, true
+	case "2":
+		return testVersioned{2}, true
+	}
+	return nil, false
+}
+
+func TestRegistryExtensionNegotiator(t *testing.T) {
+	testio := []struct {
+		inp      string
+		expected interface{}
+		err      error
+	}{
+		{"application/json", testVersioned{1}, nil},
+		{"application/json;q=1;version=2", testVersioned{2}, nil},
+		{"application/json;q=1;version=3", nil, ErrNoContentType},
+		{"application/json;q=1;version=3, application/xml", testGeneric{}, nil},
+	}
+
+	for _, policy := range []NegotiationPolicy{LegacyPolicy, StrictPolicy} {
+		reg := NewRegistryWithPolicy(policy)
+		reg.Register("application/json", &testVersioned{})
+		reg.Register("application/xml", testGeneric{})
+
+		for _, test := range testio {
+			i, _, err := reg.Negotiate(test.inp)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, i)
+		}
+	}
+}
+
+func TestRegistryNegotiateAllocations(t *testing.T) {
+	reg := NewRegistryWithPolicy(StrictPolicy)
+	for i := 0; i < 200; i++ {
+		reg.Register(fmt.Sprintf("application/vnd.vendor%d+json", i), testCodecModel{"a", i})
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		reg.Negotiate("*/*")
+	})
+	// a candidate is allocated for each registration matching */*, but the
+	// ExtensionNegotiator of each registration is not resolved again
+	assert.True(t, allocs < 300, "expected about one allocation per registration, got %v", allocs)
+}
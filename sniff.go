@@ -0,0 +1,130 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// A Detector inspects a request body and returns the media type it detects,
+// or an empty MediaType if it does not recognize the body
+type Detector func(body []byte) MediaType
+
+// utf8BOM is the byte order mark optionally prefixing UTF-8 encoded bodies
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// trimBody removes any byte order mark and leading whitespace from a body
+func trimBody(body []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(body, utf8BOM), " \t\r\n")
+}
+
+// DetectJSON detects request bodies containing a valid JSON object or array as
+// application/json
+func DetectJSON(body []byte) MediaType {
+	trimmed := trimBody(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return ""
+	}
+	if !json.Valid(trimmed) {
+		return ""
+	}
+	return "application/json"
+}
+
+// DetectXML detects request bodies beginning with an XML declaration as
+// application/xml
+func DetectXML(body []byte) MediaType {
+	if bytes.HasPrefix(trimBody(body), []byte("<?xml")) {
+		return "application/xml"
+	}
+	return ""
+}
+
+// DetectForm detects request bodies consisting solely of URL encoded
+// "key=value" pairs as application/x-www-form-urlencoded
+func DetectForm(body []byte) MediaType {
+	if len(body) == 0 || bytes.ContainsAny(body, " \t\r\n") {
+		return ""
+	}
+
+	for _, pair := range bytes.Split(body, []byte("&")) {
+		if bytes.IndexByte(pair, '=') < 1 {
+			return ""
+		}
+	}
+	if _, err := url.ParseQuery(string(body)); err != nil {
+		return ""
+	}
+	return "application/x-www-form-urlencoded"
+}
+
+// DetectContentType detects the media type of a request body using the
+// algorithm implemented by http.DetectContentType. Bodies it can not identify
+// are not detected, rather than being detected as application/octet-stream
+func DetectContentType(body []byte) MediaType {
+	typ := http.DetectContentType(body)
+	if typ == "application/octet-stream" {
+		return ""
+	}
+	return MediaType(typ)
+}
+
+// Sniffer infers the media type of request bodies sent without a Content-Type
+// header, for compatibility with lenient legacy clients. Only media types
+// registered in the Sniffer's Registry may be inferred
+type Sniffer struct {
+	Registry  *Registry
+	Detectors []Detector
+}
+
+// NewSniffer returns a Sniffer which infers media types registered in the
+// provided Registry using DetectJSON, DetectXML, DetectForm and
+// DetectContentType, in that order
+func NewSniffer(registry *Registry) *Sniffer {
+	return &Sniffer{Registry: registry,
+		Detectors: []Detector{DetectJSON, DetectXML, DetectForm, DetectContentType}}
+}
+
+// Sniff returns the ContentType detected by the first of the Sniffer's
+// Detectors whose result is registered in the Sniffer's Registry.
+// ErrNoContentType is returned if no registered media type is detected
+func (s *Sniffer) Sniff(body []byte) (*ContentType, error) {
+	for _, detect := range s.Detectors {
+		mediaType := detect(body)
+		if len(mediaType) == 0 {
+			continue
+		}
+
+		contentType, err := ParseContentType(string(mediaType))
+		if err != nil {
+			continue
+		}
+		if s.Registry.match(contentType.MediaType, MediaParams(contentType.Params)) != nil {
+			return contentType, nil
+		}
+	}
+	return nil, ErrNoContentType
+}
+
+// UnmarshalMedia handles unmarshalling an http.Request body like
+// UnmarshalMedia, but infers the media type of the body if the request has no
+// Content-Type header. ErrNoContentType is returned if the media type can not
+// be inferred
+func (s *Sniffer) UnmarshalMedia(req *http.Request, cn ContentNegotiator) error {
+	if len(req.Header.Get(ContentTypeHeader)) > 0 {
+		return UnmarshalMedia(req, cn)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	contentType, err := s.Sniff(body)
+	if err != nil {
+		return err
+	}
+	return cn.UnmarshalMedia(string(contentType.MediaType), contentType.Params, body)
+}
@@ -0,0 +1,103 @@
+package negotiator
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectors(t *testing.T) {
+	testio := []struct {
+		body    string
+		json    MediaType
+		xml     MediaType
+		form    MediaType
+		content MediaType
+	}{
+		{`{"foo": "baz", "bar": 12}`, "application/json", "", "", "text/plain; charset=utf-8"},
+		{"\xef\xbb\xbf [1, 2]", "application/json", "", "", "text/plain; charset=utf-8"},
+		{`{"foo": `, "", "", "", "text/plain; charset=utf-8"},
+		{`<?xml version="1.0"?><foo/>`, "", "application/xml", "", "text/xml; charset=utf-8"},
+		{"foo=bar&baz=1", "", "", "application/x-www-form-urlencoded", "text/plain; charset=utf-8"},
+		{"foo=bar&baz", "", "", "", "text/plain; charset=utf-8"},
+		{"hello world", "", "", "", "text/plain; charset=utf-8"},
+		{"\x89PNG\x0D\x0A\x1A\x0A", "", "", "", "image/png"},
+		{"\x00\x01\x02", "", "", "", ""},
+	}
+
+	for _, test := range testio {
+		t.Run(test.body, func(t *testing.T) {
+			body := []byte(test.body)
+			assert.Equal(t, test.json, DetectJSON(body))
+			assert.Equal(t, test.xml, DetectXML(body))
+			assert.Equal(t, test.form, DetectForm(body))
+			assert.Equal(t, test.content, DetectContentType(body))
+		})
+	}
+}
+
+func TestSnifferUnmarshalMedia(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register(testContentNegotiatorType, testCN{})
+
+	testio := []struct {
+		cType    string
+		body     string
+		err      error
+		expected testCN
+	}{
+		{testContentNegotiatorType, `{"foo": "baz", "bar": 12}`, nil, *newTcn("baz", 12)},
+		{"", `{"foo": "baz", "bar": 12}`, nil, *newTcn("baz", 12)},
+		{"", `<?xml version="1.0"?><foo/>`, ErrNoContentType, testCN{}},
+		{"", "hello world", ErrNoContentType, testCN{}},
+	}
+
+	// detect JSON bodies as the negotiated test media type
+	sniffer := NewSniffer(testReg)
+	sniffer.Detectors = append([]Detector{func(body []byte) MediaType {
+		if len(DetectJSON(body)) > 0 {
+			return testContentNegotiatorType
+		}
+		return ""
+	}}, sniffer.Detectors...)
+
+	for _, test := range testio {
+		t.Run(test.body, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", "http://example.com",
+				bytes.NewReader([]byte(test.body)))
+			if len(test.cType) > 0 {
+				req.Header.Set(ContentTypeHeader, test.cType)
+			}
+
+			var tcn testCN
+			err := sniffer.UnmarshalMedia(req, &tcn)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, tcn)
+		})
+	}
+}
+
+func TestSnifferSniff(t *testing.T) {
+	testReg := NewRegistry()
+	testReg.Register("text/plain", testGeneric{})
+	testReg.Register("application/x-www-form-urlencoded", testSpecific{})
+
+	sniffer := NewSniffer(testReg)
+	contentType, err := sniffer.Sniff([]byte("hello world"))
+	assert.Nil(t, err)
+	assert.Equal(t, MediaType("text/plain"), contentType.MediaType)
+	assert.Equal(t, "utf-8", contentType.Charset())
+
+	contentType, err = sniffer.Sniff([]byte("a=b"))
+	assert.Nil(t, err)
+	assert.Equal(t, MediaType("application/x-www-form-urlencoded"), contentType.MediaType)
+
+	_, err = sniffer.Sniff([]byte(`{"a": "b"}`))
+	assert.Nil(t, err)
+
+	sniffer.Detectors = []Detector{DetectJSON}
+	_, err = sniffer.Sniff([]byte(`{"a": "b"}`))
+	assert.Equal(t, ErrNoContentType, err)
+}
@@ -0,0 +1,241 @@
+package negotiator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// JSONMediaType is the media type of a JSON document
+	JSONMediaType = "application/json"
+
+	// NDJSONMediaType is the media type of a stream of newline delimited JSON
+	// values
+	NDJSONMediaType = "application/x-ndjson"
+
+	// JSONLinesMediaType is the media type of a stream of JSON Lines values
+	JSONLinesMediaType = "application/jsonl"
+
+	// EventStreamMediaType is the media type of a stream of Server-Sent
+	// Events
+	EventStreamMediaType = "text/event-stream"
+
+	// CacheControlHeader is the constant value for the key indicating the
+	// Cache-Control header
+	CacheControlHeader = "Cache-Control"
+)
+
+// ErrInvalidEventField is the error returned when the ID or Event of an Event
+// streamed as text/event-stream contains a carriage return or line feed, which
+// would otherwise begin a new field of the event
+var ErrInvalidEventField = errors.New("Invalid Server-Sent Event Field")
+
+// Event is a single Server-Sent Event. When streamed as text/event-stream, its
+// ID, Event and Retry fields are written alongside its JSON encoded Data. When
+// streamed in any other representation, only its Data is written. The ID and
+// Event must not contain carriage returns or line feeds
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry int
+}
+
+// A ValueIterator returns the next value of a stream, and false once the
+// stream is exhausted. An error terminates the stream
+type ValueIterator func() (interface{}, bool, error)
+
+// streamFormat is a representation a stream of values may be written as
+type streamFormat struct {
+	mediaType MediaType
+
+	// buffered formats write the entire stream once it is exhausted, rather
+	// than flushing each value as it is received
+	buffered bool
+
+	// open, write and close write the start of the stream, a single value,
+	// and the end of the stream respectively. write is provided the index of
+	// the value in the stream
+	open  func(io.Writer) error
+	write func(io.Writer, int, interface{}) error
+	close func(io.Writer) error
+}
+
+// formatType returns the media type of the stream format
+func (f streamFormat) formatType() MediaType {
+	return f.mediaType
+}
+
+// streamFormats is the Registry used to negotiate the representation of a
+// stream of values. A buffered JSON array is preferred when several
+// representations are equally acceptable
+var streamFormats = newFormatRegistry(
+	streamFormat{JSONMediaType, true, writeString("["), writeJSONArrayValue, writeString("]\n")},
+	streamFormat{NDJSONMediaType, false, writeString(""), writeJSONLine, writeString("")},
+	streamFormat{JSONLinesMediaType, false, writeString(""), writeJSONLine, writeString("")},
+	streamFormat{EventStreamMediaType, false, writeString(""), writeEvent, writeString("")},
+)
+
+// writeString returns a function which writes the provided string
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// eventData returns the data of a value, unwrapping any Event
+func eventData(val interface{}) interface{} {
+	switch event := val.(type) {
+	case Event:
+		return event.Data
+	case *Event:
+		return event.Data
+	}
+	return val
+}
+
+// writeJSONArrayValue writes a value as an element of a JSON array
+func writeJSONArrayValue(w io.Writer, idx int, val interface{}) error {
+	data, err := json.Marshal(eventData(val))
+	if err != nil {
+		return err
+	}
+	if idx > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeJSONLine writes a value as a single line of JSON
+func writeJSONLine(w io.Writer, _ int, val interface{}) error {
+	data, err := json.Marshal(eventData(val))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeEvent writes a value as a Server-Sent Event
+func writeEvent(w io.Writer, _ int, val interface{}) error {
+	event, ok := val.(Event)
+	if ptr, isPtr := val.(*Event); isPtr {
+		event, ok = *ptr, true
+	}
+	if !ok {
+		event = Event{Data: val}
+	}
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return ErrInvalidEventField
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if len(event.ID) > 0 {
+		fmt.Fprintf(&buf, "id: %s\n", event.ID)
+	}
+	if len(event.Event) > 0 {
+		fmt.Fprintf(&buf, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", event.Retry)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// Stream writes the values received from the provided channel to w, until the
+// channel is closed, in the representation negotiated from the request's
+// Accept header. See StreamIterator for details
+func Stream(w http.ResponseWriter, req *http.Request, values <-chan interface{}) error {
+	return StreamIterator(w, req, func() (interface{}, bool, error) {
+		select {
+		case val, ok := <-values:
+			return val, ok, nil
+		case <-req.Context().Done():
+			return nil, false, req.Context().Err()
+		}
+	})
+}
+
+// StreamIterator writes the values returned by the provided ValueIterator to
+// w, in the representation negotiated from the request's Accept header. A
+// JSON array is buffered until the iterator is exhausted, while
+// application/x-ndjson, application/jsonl and text/event-stream values are
+// flushed to the client as they are produced. ErrNoContentType is returned,
+// and nothing is written, if none of these representations are acceptable.
+// The stream ends early if the request's context is done
+func StreamIterator(w http.ResponseWriter, req *http.Request, next ValueIterator) error {
+	f, err := negotiateFormat(streamFormats, req)
+	if err != nil {
+		return err
+	}
+	format := f.(streamFormat)
+	AddVary(w.Header(), AcceptHeaderKey)
+
+	var out io.Writer = w
+	var buf bytes.Buffer
+	if format.buffered {
+		out = &buf
+	} else {
+		w.Header().Set(ContentTypeHeader, string(format.mediaType))
+		w.Header().Set(CacheControlHeader, "no-cache")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok && !format.buffered {
+			f.Flush()
+		}
+	}
+
+	if err := format.open(out); err != nil {
+		return err
+	}
+	for idx := 0; ; idx++ {
+		if err := req.Context().Err(); err != nil {
+			return err
+		}
+
+		val, ok, err := next()
+		if err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+
+		if err := format.write(out, idx, val); err != nil {
+			return err
+		}
+		flush()
+	}
+	if err := format.close(out); err != nil {
+		return err
+	}
+
+	if format.buffered {
+		w.Header().Set(ContentTypeHeader, string(format.mediaType))
+		w.WriteHeader(http.StatusOK)
+		_, err = buf.WriteTo(w)
+		return err
+	}
+	flush()
+	return nil
+}
@@ -0,0 +1,108 @@
+package negotiator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStreamValue struct {
+	N int `json:"n"`
+}
+
+func TestStream(t *testing.T) {
+	testio := []struct {
+		accept      string
+		contentType string
+		body        string
+		err         error
+	}{
+		{"", JSONMediaType, `[{"n":1},{"n":2},{"n":3}]` + "\n", nil},
+		{"application/json", JSONMediaType, `[{"n":1},{"n":2},{"n":3}]` + "\n", nil},
+		{"application/x-ndjson", NDJSONMediaType, "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n", nil},
+		{"application/jsonl, application/json;q=0.5", JSONLinesMediaType,
+			"{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n", nil},
+		{"text/event-stream", EventStreamMediaType,
+			"data: {\"n\":1}\n\nid: 2\nevent: update\ndata: {\"n\":2}\n\nretry: 10\ndata: {\"n\":3}\n\n", nil},
+		{"text/html", "", "", ErrNoContentType},
+	}
+
+	for _, test := range testio {
+		t.Run(test.accept, func(t *testing.T) {
+			values := make(chan interface{}, 3)
+			values <- testStreamValue{1}
+			values <- Event{ID: "2", Event: "update", Data: testStreamValue{2}}
+			values <- &Event{Retry: 10, Data: testStreamValue{3}}
+			close(values)
+
+			req := httptest.NewRequest("GET", "http://example.com/events", nil)
+			req.Header.Set(AcceptHeaderKey, test.accept)
+			w := httptest.NewRecorder()
+
+			err := Stream(w, req, values)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.contentType, w.Header().Get(ContentTypeHeader))
+			assert.Equal(t, test.body, w.Body.String())
+			if err == nil {
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
+func TestStreamIteratorFlushes(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/events", nil)
+	req.Header.Set(AcceptHeaderKey, NDJSONMediaType)
+	w := httptest.NewRecorder()
+
+	var flushed []string
+	n := 0
+	err := StreamIterator(w, req, func() (interface{}, bool, error) {
+		flushed = append(flushed, w.Body.String())
+		n++
+		return testStreamValue{n}, n <= 2, nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, []string{"", "{\"n\":1}\n", "{\"n\":1}\n{\"n\":2}\n"}, flushed)
+}
+
+func TestStreamIteratorErrors(t *testing.T) {
+	errIterator := errors.New("iterator failed")
+	req := httptest.NewRequest("GET", "http://example.com/events", nil)
+	w := httptest.NewRecorder()
+
+	err := StreamIterator(w, req, func() (interface{}, bool, error) {
+		return nil, false, errIterator
+	})
+	assert.Equal(t, errIterator, err)
+	assert.Equal(t, 0, w.Body.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Stream(httptest.NewRecorder(), req.WithContext(ctx), make(chan interface{}))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestStreamEventFieldInjection(t *testing.T) {
+	for _, event := range []Event{
+		{ID: "1\ndata: injected", Data: testStreamValue{1}},
+		{Event: "update\r\ndata: injected", Data: testStreamValue{1}},
+		{ID: "1\r", Data: testStreamValue{1}},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/events", nil)
+		req.Header.Set(AcceptHeaderKey, EventStreamMediaType)
+		w := httptest.NewRecorder()
+
+		values := make(chan interface{}, 1)
+		values <- event
+		close(values)
+
+		assert.Equal(t, ErrInvalidEventField, Stream(w, req, values))
+		assert.NotContains(t, w.Body.String(), "injected")
+	}
+}
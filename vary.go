@@ -0,0 +1,71 @@
+package negotiator
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// VaryHeader is the constant value for the key indicating the Vary header
+	VaryHeader = "Vary"
+
+	// AcceptLanguageHeader is the constant value for the key indicating the
+	// Accept-Language header. This package doesn't negotiate it, so callers
+	// selecting a representation by language must pass it to AddVary
+	AcceptLanguageHeader = "Accept-Language"
+
+	// AcceptEncodingHeader is the constant value for the key indicating the
+	// Accept-Encoding header. This package doesn't negotiate it, so callers
+	// selecting a representation by content coding must pass it to AddVary
+	AcceptEncodingHeader = "Accept-Encoding"
+
+	// AcceptCharsetHeader is the constant value for the key indicating the
+	// Accept-Charset header. This package doesn't negotiate it, so callers
+	// selecting a representation by charset must pass it to AddVary
+	AcceptCharsetHeader = "Accept-Charset"
+)
+
+// AddVary merges the provided request header names into the Vary header of h.
+// Values already present, including those set by other middleware, are kept,
+// names are only listed once regardless of case, and nothing is added once
+// the Vary header contains "*". Multiple Vary header lines are merged into a
+// single line
+func AddVary(h http.Header, headers ...string) {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		key := http.CanonicalHeaderKey(name)
+		if len(name) == 0 || seen[key] {
+			return
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+
+	for _, line := range h[VaryHeader] {
+		for _, name := range strings.Split(line, ",") {
+			add(name)
+		}
+	}
+	if !seen[WildCard] {
+		for _, name := range headers {
+			add(http.CanonicalHeaderKey(name))
+		}
+	}
+
+	if len(names) > 0 {
+		h.Set(VaryHeader, strings.Join(names, ", "))
+	}
+}
+
+// NegotiateResponse negotiates the representation of the provided request
+// like NegotiateRequest, and adds the request headers which influenced the
+// decision to the Vary header of w, even if negotiation fails. The Accept
+// header is omitted if a format override was negotiated without consulting
+// it, as the override is part of the request's URL
+func (r *Registry) NegotiateResponse(w http.ResponseWriter, req *http.Request) (interface{}, *Accept, error) {
+	val, acpt, headers, err := r.negotiateRequest(req)
+	AddVary(w.Header(), headers...)
+	return val, acpt, err
+}
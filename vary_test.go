@@ -0,0 +1,114 @@
+package negotiator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddVary(t *testing.T) {
+	testio := []struct {
+		existing []string
+		headers  []string
+		expected []string
+	}{
+		{nil, nil, nil},
+		{nil, []string{"accept"}, []string{"Accept"}},
+		{nil, []string{"Accept", "accept-language", "Accept"}, []string{"Accept, Accept-Language"}},
+		{[]string{"Origin"}, []string{"Accept"}, []string{"Origin, Accept"}},
+		{[]string{"Origin, accept", "Cookie"}, []string{AcceptHeaderKey, AcceptEncodingHeader},
+			[]string{"Origin, accept, Cookie, Accept-Encoding"}},
+		{[]string{"*"}, []string{"Accept"}, []string{"*"}},
+		{[]string{"Origin"}, nil, []string{"Origin"}},
+	}
+
+	for _, test := range testio {
+		h := make(http.Header)
+		for _, line := range test.existing {
+			h.Add(VaryHeader, line)
+		}
+		AddVary(h, test.headers...)
+		assert.Equal(t, test.expected, h[VaryHeader])
+	}
+}
+
+func TestRegistryNegotiateResponse(t *testing.T) {
+	testio := []struct {
+		url        string
+		accept     string
+		override   bool
+		precedence FormatPrecedence
+		vary       string
+		err        error
+	}{
+		{"/messages", "text/csv", false, OverrideFirst, "Origin, Accept", nil},
+		{"/messages", "", false, OverrideFirst, "Origin, Accept", nil},
+		{"/messages", "image/png", false, OverrideFirst, "Origin, Accept", ErrNoContentType},
+		{"/messages", "text/csv", true, OverrideFirst, "Origin, Accept", nil},
+		{"/messages.csv", "application/json", true, OverrideFirst, "Origin", nil},
+		{"/messages?format=bogus", "application/json", true, OverrideFirst, "Origin", ErrNoContentType},
+		{"/messages.csv", "application/json", true, AcceptFirst, "Origin, Accept", nil},
+		{"/messages.csv", "", true, AcceptFirst, "Origin, Accept", nil},
+	}
+
+	for _, test := range testio {
+		testReg := NewRegistry()
+		testReg.Register("application/json", testGeneric{})
+		testReg.Register("text/csv", testSpecific{})
+		if test.override {
+			f := NewFormatOverride()
+			f.Precedence = test.precedence
+			testReg.SetFormatOverride(f)
+		}
+
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Header.Set(AcceptHeaderKey, test.accept)
+		w := httptest.NewRecorder()
+		w.Header().Set(VaryHeader, "Origin")
+
+		_, _, err := testReg.NegotiateResponse(w, req)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.vary, w.Header().Get(VaryHeader))
+	}
+}
+
+func TestResponseWritersVary(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptHeaderKey, "application/json")
+
+	writers := []func(http.ResponseWriter) error{
+		func(w http.ResponseWriter) error {
+			return WriteProblem(w, req, NewProblem(http.StatusBadRequest, ""))
+		},
+		func(w http.ResponseWriter) error {
+			return NotAcceptable(w, req, NewRegistry())
+		},
+		func(w http.ResponseWriter) error {
+			return MultipleChoices(w, req, nil)
+		},
+		func(w http.ResponseWriter) error {
+			return StreamIterator(w, req, func() (interface{}, bool, error) {
+				return nil, false, nil
+			})
+		},
+		func(w http.ResponseWriter) error {
+			acpt, _ := ParseAccept(testContentNegotiatorType)
+			return WriteConditional(w, req, newTcn("foo", 1), acpt, false)
+		},
+		func(w http.ResponseWriter) error {
+			acpt, _ := ParseAccept(testContentNegotiatorType)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(IfNoneMatchHeader, "*")
+			return WriteConditional(w, req, newTcn("foo", 1), acpt, false)
+		},
+	}
+
+	for _, write := range writers {
+		w := httptest.NewRecorder()
+		w.Header().Add(VaryHeader, AcceptEncodingHeader)
+		assert.Nil(t, write(w))
+		assert.Equal(t, "Accept-Encoding, Accept", w.Header().Get(VaryHeader))
+	}
+}